@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/build/constraint"
 	"go/doc"
 	"go/parser"
 	"go/token"
@@ -51,6 +52,15 @@ type Context struct {
 
 	IgnoreTags []string // ignore files with list of tags
 
+	// Targets are platforms the build is for. If Targets is not empty, a
+	// file is used if it matches any of them with cgo enabled or disabled,
+	// UseAllFiles is not honoured then and IgnoreTags are never satisfied.
+	Targets []Target
+
+	// VendorDir is the name of vendor directories outside of Go root,
+	// "vendor" if empty.
+	VendorDir string
+
 	// The install suffix specifies a suffix to use in the name of the installation
 	// directory. By default it is empty, but custom builds that need to keep
 	// their outputs separate can set InstallSuffix to do so. For example, when
@@ -291,19 +301,23 @@ type Package struct {
 	BinaryOnly    bool     // cannot be rebuilt from source (has //go:binary-only-package comment)
 
 	// Source files
-	GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
-	CgoFiles       []string // .go source files that import "C"
-	IgnoredGoFiles []string // .go source files ignored for this build
-	InvalidGoFiles []string // .go source files with detected problems (parse error, wrong package name, and so on)
-	CFiles         []string // .c source files
-	CXXFiles       []string // .cc, .cpp and .cxx source files
-	MFiles         []string // .m (Objective-C) source files
-	HFiles         []string // .h, .hh, .hpp and .hxx source files
-	FFiles         []string // .f, .F, .for and .f90 Fortran source files
-	SFiles         []string // .s source files
-	SwigFiles      []string // .swig files
-	SwigCXXFiles   []string // .swigcxx files
-	SysoFiles      []string // .syso system object files to add to archive
+	GoFiles           []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
+	CgoFiles          []string // .go source files that import "C"
+	IgnoredGoFiles    []string // .go source files ignored for this build
+	IgnoredOtherFiles []string // non-.go source files ignored for this build
+	InvalidGoFiles    []string // .go source files with detected problems (parse error, wrong package name, and so on)
+	CFiles            []string // .c source files
+	CXXFiles          []string // .cc, .cpp and .cxx source files
+	MFiles            []string // .m (Objective-C) source files
+	HFiles            []string // .h, .hh, .hpp and .hxx source files
+	FFiles            []string // .f, .F, .for and .f90 Fortran source files
+	SFiles            []string // .s source files
+	SwigFiles         []string // .swig files
+	SwigCXXFiles      []string // .swigcxx files
+	SysoFiles         []string // .syso system object files to add to archive
+
+	// Embedded files
+	EmbedPatterns []string // patterns from //go:embed directives of non-test files
 
 	// Cgo directives
 	CgoCFLAGS    []string // Cgo CFLAGS directives
@@ -363,6 +377,17 @@ func (e *MultiplePackageError) Error() string {
 	return fmt.Sprintf("found packages %s (%s) and %s (%s) in %s", e.Packages[0], e.Files[0], e.Packages[1], e.Files[1], e.Dir)
 }
 
+// constraintError describes a file whose build constraints can't be parsed.
+// Import records such files in InvalidGoFiles without failing the package.
+type constraintError struct {
+	file string
+	err  error
+}
+
+func (e *constraintError) Error() string {
+	return e.file + ": " + e.err.Error()
+}
+
 func nameExt(name string) string {
 	i := strings.LastIndex(name, ".")
 	if i < 0 {
@@ -494,13 +519,17 @@ func (ctxt *Context) Import(path, srcDir string, mode ImportMode) (*Package, err
 				if !ok || !strings.HasPrefix(sub, "src/") || strings.Contains(sub, "/testdata/") {
 					return false
 				}
+				vendorDir := "vendor"
+				if ctxt.VendorDir != "" && !isGoroot {
+					vendorDir = ctxt.VendorDir
+				}
 				for {
-					vendor := ctxt.joinPath(root, sub, "vendor")
+					vendor := ctxt.joinPath(root, sub, vendorDir)
 					if ctxt.isDir(vendor) {
 						dir := ctxt.joinPath(vendor, path)
 						if ctxt.isDir(dir) && hasGoFiles(ctxt, dir) {
 							p.Dir = dir
-							p.ImportPath = strings.TrimPrefix(pathpkg.Join(sub, "vendor", path), "src/")
+							p.ImportPath = strings.TrimPrefix(pathpkg.Join(sub, vendorDir, path), "src/")
 							p.Goroot = isGoroot
 							p.Root = root
 							setPkga() // p.ImportPath changed
@@ -603,6 +632,7 @@ Found:
 	imported := make(map[string][]token.Position)
 	testImported := make(map[string][]token.Position)
 	xTestImported := make(map[string][]token.Position)
+	embedPatterns := make(map[string]bool)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
 	for _, d := range dirs {
@@ -621,6 +651,10 @@ Found:
 		}
 
 		match, data, filename, err := ctxt.matchFile(p.Dir, name, true, allTags, &p.BinaryOnly)
+		if _, ok := err.(*constraintError); ok {
+			p.InvalidGoFiles = append(p.InvalidGoFiles, name)
+			continue
+		}
 		if err != nil {
 			badFile(err)
 			continue
@@ -628,6 +662,8 @@ Found:
 		if !match {
 			if ext == ".go" {
 				p.IgnoredGoFiles = append(p.IgnoredGoFiles, name)
+			} else if isSourceExt(ext) {
+				p.IgnoredOtherFiles = append(p.IgnoredOtherFiles, name)
 			}
 			continue
 		}
@@ -720,6 +756,7 @@ Found:
 
 		// Record imports and information about cgo.
 		isCgo := false
+		usesEmbed := false
 		for _, decl := range pf.Decls {
 			d, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -735,6 +772,9 @@ Found:
 				if err != nil {
 					log.Panicf("%s: parser returned invalid quoted string: <%s>", filename, quoted)
 				}
+				if path == "embed" {
+					usesEmbed = true
+				}
 				if isXTest {
 					xTestImported[path] = append(xTestImported[path], fset.Position(spec.Pos()))
 				} else if isTest {
@@ -760,6 +800,16 @@ Found:
 				}
 			}
 		}
+		if usesEmbed && !isTest {
+			patterns, err := ctxt.readEmbedPatterns(filename)
+			if err != nil {
+				badFile(err)
+				continue
+			}
+			for _, pattern := range patterns {
+				embedPatterns[pattern] = true
+			}
+		}
 		switch {
 		case isCgo:
 			allTags["cgo"] = true
@@ -791,6 +841,10 @@ Found:
 	p.Imports, p.ImportPos = cleanImports(imported)
 	p.TestImports, p.TestImportPos = cleanImports(testImported)
 	p.XTestImports, p.XTestImportPos = cleanImports(xTestImported)
+	for pattern := range embedPatterns {
+		p.EmbedPatterns = append(p.EmbedPatterns, pattern)
+	}
+	sort.Strings(p.EmbedPatterns)
 
 	// add the .S files only if we are using cgo
 	// (which means gcc will compile them).
@@ -803,6 +857,105 @@ Found:
 	return p, pkgerr
 }
 
+var goEmbedComment = "//go:embed"
+
+// readEmbedPatterns returns patterns of //go:embed directives of Go file
+// filename. The whole file is read, because directives follow imports.
+func (ctxt *Context) readEmbedPatterns(filename string) ([]string, error) {
+	f, err := ctxt.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", filename, err)
+	}
+	pf, err := parser.ParseFile(token.NewFileSet(), filename, data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	for _, cg := range pf.Comments {
+		for _, c := range cg.List {
+			if !strings.HasPrefix(c.Text, goEmbedComment) {
+				continue
+			}
+			args := c.Text[len(goEmbedComment):]
+			if r, _ := utf8.DecodeRuneInString(args); args != "" && !unicode.IsSpace(r) {
+				continue
+			}
+			list, err := parseGoEmbed(args)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", filename, err)
+			}
+			patterns = append(patterns, list...)
+		}
+	}
+	return patterns, nil
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the glob
+// patterns. It accepts unquoted space-separated patterns as well as
+// double-quoted and back-quoted Go strings.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var path string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			path = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			path = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					path = q
+					args = args[i+1:]
+					break Switch
+				}
+			}
+			if i >= len(args) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, path)
+	}
+	return list, nil
+}
+
 // hasGoFiles reports whether dir contains any files with names ending in .go.
 // For a vendor check we must exclude directories that contain no .go files.
 // Otherwise it is not possible to vendor just a/b/c and still import the
@@ -959,7 +1112,17 @@ func (ctxt *Context) matchFile(dir, name string, returnImports bool, allTags map
 	}
 	ext := name[i:]
 
-	if !ctxt.goodOSArchFile(name, allTags) && !ctxt.UseAllFiles {
+	if len(ctxt.Targets) > 0 {
+		good := false
+		for _, tc := range ctxt.targets() {
+			if tc.goodOSArchFile(name, allTags) {
+				good = true
+			}
+		}
+		if !good {
+			return
+		}
+	} else if !ctxt.goodOSArchFile(name, allTags) && !ctxt.UseAllFiles {
 		return
 	}
 
@@ -996,19 +1159,40 @@ func (ctxt *Context) matchFile(dir, name string, returnImports bool, allTags map
 		return
 	}
 
-	// Look for +build comments to accept or reject the file.
+	// Look for //go:build and +build comments to accept or reject the file.
 	fileTags := make(map[string]bool)
-	if !ctxt.shouldBuild(data, fileTags, binaryOnly) {
+	if len(ctxt.Targets) > 0 {
+		for _, tc := range ctxt.targets() {
+			ok, berr := tc.shouldBuild(data, fileTags, binaryOnly)
+			if berr != nil {
+				err = &constraintError{name, berr}
+				return
+			}
+			if ok {
+				match = true
+			}
+		}
+		for name, value := range fileTags {
+			allTags[name] = value
+		}
+		return
+	}
+	ok, err := ctxt.shouldBuild(data, fileTags, binaryOnly)
+	if err != nil {
+		err = &constraintError{name, err}
+		return
+	}
+	if !ok {
 		// merge fileTags with allTags
+		ignored := false
 		for name, value := range fileTags {
 			allTags[name] = value
-
-			// IgnoreTags took precendent before UseAllFiles
 			if ctxt.matchIgnoreTags(name) {
-				return
+				ignored = true
 			}
 		}
-		if !ctxt.UseAllFiles {
+		// IgnoreTags took precendent before UseAllFiles
+		if !ctxt.UseAllFiles || (ignored && ctxt.needsIgnoredTag(data)) {
 			return
 		}
 	}
@@ -1017,6 +1201,113 @@ func (ctxt *Context) matchFile(dir, name string, returnImports bool, allTags map
 	return
 }
 
+// needsIgnoredTag reports whether build constraints of file content can be
+// satisfied only if some of IgnoreTags is set. Ignored tags are unset and
+// other tags can have any values, so a file for "integration" tag is ignored,
+// but a file for "windows && !integration" is not.
+func (ctxt *Context) needsIgnoredTag(content []byte) bool {
+	content, goBuild, _, err := parseFileHeader(content)
+	if err != nil {
+		return false
+	}
+	var x constraint.Expr
+	if goBuild != nil {
+		if x, err = constraint.Parse(string(goBuild)); err != nil {
+			return false
+		}
+	} else {
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if !constraint.IsPlusBuild(line) {
+				continue
+			}
+			y, err := constraint.Parse(line)
+			if err != nil {
+				return false
+			}
+			if x == nil {
+				x = y
+			} else {
+				x = &constraint.AndExpr{X: x, Y: y}
+			}
+		}
+	}
+	if x == nil {
+		return false
+	}
+	var free []string
+	seen := make(map[string]bool)
+	x.Eval(func(tag string) bool {
+		if !seen[tag] && !ctxt.matchIgnoreTags(tag) {
+			free = append(free, tag)
+		}
+		seen[tag] = true
+		return false
+	})
+	// too many tags to try all their values, the file is kept
+	if len(free) > 16 {
+		return false
+	}
+	for set := 0; set < 1<<uint(len(free)); set++ {
+		values := make(map[string]bool)
+		for i, tag := range free {
+			values[tag] = set&(1<<uint(i)) != 0
+		}
+		if x.Eval(func(tag string) bool { return values[tag] }) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSourceExt reports whether ext is extension of non-Go source files which
+// are built with package.
+func isSourceExt(ext string) bool {
+	switch ext {
+	case ".c", ".cc", ".cxx", ".cpp", ".m", ".s", ".h", ".hh", ".hpp", ".hxx", ".f", ".F", ".for", ".f90", ".S", ".swig", ".swigcxx", ".syso":
+		return true
+	}
+	return false
+}
+
+// A Target is a platform the build is for.
+type Target struct {
+	GOOS      string
+	GOARCH    string
+	BuildTags []string // build tags in addition to BuildTags of the context
+}
+
+// targets returns copies of ctxt for building for each of Targets with cgo
+// enabled and disabled.
+func (ctxt *Context) targets() []*Context {
+	var cs []*Context
+	for _, t := range ctxt.Targets {
+		for _, cgo := range []bool{true, false} {
+			c := *ctxt
+			c.GOOS = t.GOOS
+			c.GOARCH = t.GOARCH
+			c.CgoEnabled = cgo
+			c.BuildTags = nil
+			for _, tag := range append(ctxt.BuildTags[:len(ctxt.BuildTags):len(ctxt.BuildTags)], t.BuildTags...) {
+				// ignored tags are never satisfied
+				if !ctxt.matchIgnoreTags(tag) {
+					c.BuildTags = append(c.BuildTags, tag)
+				}
+			}
+			c.UseAllFiles = false
+			c.Targets = nil
+			cs = append(cs, &c)
+		}
+	}
+	return cs
+}
+
+// KnownPlatform reports whether goos and goarch are known operating system and
+// architecture.
+func KnownPlatform(goos, goarch string) bool {
+	return knownOS[goos] && knownArch[goarch]
+}
+
 func cleanImports(m map[string][]token.Position) ([]string, map[string][]token.Position) {
 	all := make([]string, 0, len(m))
 	for path := range m {
@@ -1033,6 +1324,19 @@ var slashslash = []byte("//")
 // for more about the design of binary-only packages.
 var binaryOnlyComment = []byte("//go:binary-only-package")
 
+var goBuildComment = []byte("//go:build")
+
+var errMultipleGoBuild = errors.New("multiple //go:build comments")
+
+func isGoBuildComment(line []byte) bool {
+	if !bytes.HasPrefix(line, goBuildComment) {
+		return false
+	}
+	line = bytes.TrimSpace(line)
+	rest := line[len(goBuildComment):]
+	return len(rest) == 0 || len(bytes.TrimSpace(rest)) < len(rest)
+}
+
 // shouldBuild reports whether it is okay to use this file,
 // The rule is that in the file's leading run of // comments
 // and blank lines, which must be followed by a blank line
@@ -1046,49 +1350,49 @@ var binaryOnlyComment = []byte("//go:binary-only-package")
 //
 // marks the file as applicable only on Windows and Linux.
 //
+// A //go:build line before the package clause takes precedence over
+// '// +build' lines, its expression is evaluated instead of them:
+//
+//	//go:build (linux || windows) && !cgo
+//
+// Tags of the expression are matched like tags of '// +build' lines, so
+// they are recorded in allTags even if the expression is decided
+// without them.
+//
 // If shouldBuild finds a //go:binary-only-package comment in a file that
 // should be built, it sets *binaryOnly to true. Otherwise it does
 // not change *binaryOnly.
 //
-func (ctxt *Context) shouldBuild(content []byte, allTags map[string]bool, binaryOnly *bool) bool {
-	sawBinaryOnly := false
-
-	// Pass 1. Identify leading run of // comments and blank lines,
+func (ctxt *Context) shouldBuild(content []byte, allTags map[string]bool, binaryOnly *bool) (bool, error) {
+	// Identify leading run of // comments and blank lines,
 	// which must be followed by a blank line.
-	end := 0
-	p := content
-	for len(p) > 0 {
-		line := p
-		if i := bytes.IndexByte(line, '\n'); i >= 0 {
-			line, p = line[:i], p[i+1:]
-		} else {
-			p = p[len(p):]
-		}
-		line = bytes.TrimSpace(line)
-		if len(line) == 0 { // Blank line
-			end = len(content) - len(p)
-			continue
-		}
-		if !bytes.HasPrefix(line, slashslash) { // Not comment line
-			break
-		}
+	// Also identify any //go:build comments.
+	content, goBuild, sawBinaryOnly, err := parseFileHeader(content)
+	if err != nil {
+		return false, err
 	}
-	content = content[:end]
 
-	// Pass 2.  Process each line in the run.
-	p = content
+	// If //go:build line is present, it controls.
+	// Otherwise fall back to +build processing.
 	allok := true
-	for len(p) > 0 {
-		line := p
-		if i := bytes.IndexByte(line, '\n'); i >= 0 {
-			line, p = line[:i], p[i+1:]
-		} else {
-			p = p[len(p):]
+	if goBuild != nil {
+		x, err := constraint.Parse(string(goBuild))
+		if err != nil {
+			return false, fmt.Errorf("parsing //go:build line: %v", err)
 		}
-		line = bytes.TrimSpace(line)
-		if bytes.HasPrefix(line, slashslash) {
-			if bytes.Equal(line, binaryOnlyComment) {
-				sawBinaryOnly = true
+		allok = x.Eval(func(tag string) bool { return ctxt.match(tag, allTags) })
+	} else {
+		p := content
+		for len(p) > 0 {
+			line := p
+			if i := bytes.IndexByte(line, '\n'); i >= 0 {
+				line, p = line[:i], p[i+1:]
+			} else {
+				p = p[len(p):]
+			}
+			line = bytes.TrimSpace(line)
+			if !bytes.HasPrefix(line, slashslash) {
+				continue
 			}
 			line = bytes.TrimSpace(line[len(slashslash):])
 			if len(line) > 0 && line[0] == '+' {
@@ -1113,7 +1417,72 @@ func (ctxt *Context) shouldBuild(content []byte, allTags map[string]bool, binary
 		*binaryOnly = true
 	}
 
-	return allok
+	return allok, nil
+}
+
+// parseFileHeader returns the leading run of // comments and blank lines of
+// content, which is followed by a blank line, and //go:build line if there is
+// one before the first non-comment text.
+func parseFileHeader(content []byte) (trimmed, goBuild []byte, sawBinaryOnly bool, err error) {
+	end := 0
+	p := content
+	ended := false       // found non-blank, non-// line, so stopped accepting // +build lines
+	inSlashStar := false // in /* */ comment
+
+Lines:
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, p = line[:i], p[i+1:]
+		} else {
+			p = p[len(p):]
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 && !ended { // Blank line
+			// Remember position of most recent blank line.
+			// When we find the first non-blank, non-comment line,
+			// we'll cut p at the last blank line before that.
+			end = len(content) - len(p)
+			continue Lines
+		}
+		if !bytes.HasPrefix(line, slashslash) { // Not comment line
+			ended = true
+		}
+
+		if !inSlashStar && isGoBuildComment(line) {
+			if goBuild != nil {
+				return nil, nil, false, errMultipleGoBuild
+			}
+			goBuild = line
+		}
+		if !inSlashStar && bytes.Equal(line, binaryOnlyComment) {
+			sawBinaryOnly = true
+		}
+
+		// Comment-stripping loop
+		for len(line) > 0 {
+			if inSlashStar {
+				if i := bytes.Index(line, starSlash); i >= 0 {
+					inSlashStar = false
+					line = bytes.TrimSpace(line[i+len(starSlash):])
+					continue
+				}
+				continue Lines
+			}
+			if bytes.HasPrefix(line, slashslash) {
+				continue Lines
+			}
+			if bytes.HasPrefix(line, slashStar) {
+				inSlashStar = true
+				line = bytes.TrimSpace(line[len(slashStar):])
+				continue
+			}
+			// Found non-comment text.
+			break Lines
+		}
+	}
+
+	return content[:end], goBuild, sawBinaryOnly, nil
 }
 
 // saveCgo saves the information from the #cgo lines in the import "C" comment.
@@ -1368,6 +1737,15 @@ func (ctxt *Context) match(name string, allTags map[string]bool) bool {
 	if ctxt.GOOS == "android" && name == "linux" {
 		return true
 	}
+	if ctxt.GOOS == "illumos" && name == "solaris" {
+		return true
+	}
+	if ctxt.GOOS == "ios" && name == "darwin" {
+		return true
+	}
+	if name == "unix" && unixOS[ctxt.GOOS] {
+		return true
+	}
 
 	// other tags
 	for _, tag := range ctxt.BuildTags {
@@ -1395,7 +1773,8 @@ func (ctxt *Context) match(name string, allTags map[string]bool) bool {
 //     name_$(GOARCH)_test.*
 //     name_$(GOOS)_$(GOARCH)_test.*
 //
-// An exception: if GOOS=android, then files with GOOS=linux are also matched.
+// An exception: if GOOS=android, then files with GOOS=linux are also matched,
+// the same holds for illumos and solaris, ios and darwin.
 func (ctxt *Context) goodOSArchFile(name string, allTags map[string]bool) bool {
 	if dot := strings.Index(name, "."); dot != -1 {
 		name = name[:dot]
@@ -1427,19 +1806,13 @@ func (ctxt *Context) goodOSArchFile(name string, allTags map[string]bool) bool {
 		if l[n-1] != ctxt.GOARCH {
 			return false
 		}
-		if ctxt.GOOS == "android" && l[n-2] == "linux" {
-			return true
-		}
-		return l[n-2] == ctxt.GOOS
+		return ctxt.matchOSFile(l[n-2])
 	}
 	if n >= 1 && knownOS[l[n-1]] {
 		if allTags != nil {
 			allTags[l[n-1]] = true
 		}
-		if ctxt.GOOS == "android" && l[n-1] == "linux" {
-			return true
-		}
-		return l[n-1] == ctxt.GOOS
+		return ctxt.matchOSFile(l[n-1])
 	}
 	if n >= 1 && knownArch[l[n-1]] {
 		if allTags != nil {
@@ -1450,8 +1823,26 @@ func (ctxt *Context) goodOSArchFile(name string, allTags map[string]bool) bool {
 	return true
 }
 
+// matchOSFile reports whether file name suffix goos matches GOOS of ctxt.
+// Files of linux are matched on android, files of solaris on illumos and files
+// of darwin on ios.
+func (ctxt *Context) matchOSFile(goos string) bool {
+	switch {
+	case goos == ctxt.GOOS:
+		return true
+	case ctxt.GOOS == "android" && goos == "linux":
+		return true
+	case ctxt.GOOS == "illumos" && goos == "solaris":
+		return true
+	case ctxt.GOOS == "ios" && goos == "darwin":
+		return true
+	}
+	return false
+}
+
 var knownOS = make(map[string]bool)
 var knownArch = make(map[string]bool)
+var unixOS = make(map[string]bool)
 
 func init() {
 	for _, v := range strings.Fields(goosList) {
@@ -1460,6 +1851,9 @@ func init() {
 	for _, v := range strings.Fields(goarchList) {
 		knownArch[v] = true
 	}
+	for _, v := range strings.Fields(unixOSList) {
+		unixOS[v] = true
+	}
 }
 
 // ToolDir is the directory containing build tools.
@@ -91,6 +91,14 @@
 //
 //	(linux OR darwin) AND 386
 //
+// Since Go 1.17 a constraint may be written as a //go:build line with a
+// boolean expression using ||, &&, ! and parentheses:
+//
+//	//go:build (linux && 386) || (darwin && !cgo)
+//
+// A file may have only one //go:build line. If it is present, it takes
+// precedence and the // +build lines of the file are not evaluated.
+//
 // During a particular build, the following words are satisfied:
 //
 //	- the target operating system, as spelled by runtime.GOOS
@@ -3,6 +3,7 @@ package main
 import (
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -70,15 +71,141 @@ func isVendorConfFile(path string) bool {
 	}
 }
 
-// cleanVendor removes files from unused packages and non-go files
-func cleanVendor(vendorDir string, realDeps []*build.Package) error {
+// depOwner returns dependency from deps which contains path relPath of vendor
+// dir and path relative to its root.
+func depOwner(deps []depEntry, relPath string) (depEntry, string, bool) {
+	p := filepath.ToSlash(relPath)
+	var owner depEntry
+	var found bool
+	for _, d := range deps {
+		if strings.HasPrefix(p, d.importPath+"/") && len(d.importPath) > len(owner.importPath) {
+			owner, found = d, true
+		}
+	}
+	return owner, strings.TrimPrefix(p, owner.importPath+"/"), found
+}
+
+// matchPattern reports whether keep or drop pattern matches path rel of
+// dependency. Pattern without slash matches name of file or any of its parent
+// directories, other patterns match path relative to dependency root or any of
+// its parents.
+func matchPattern(pattern, rel string) bool {
+	pattern = strings.Trim(pattern, "/")
+	elems := strings.Split(rel, "/")
+	for i := range elems {
+		var target string
+		if strings.Contains(pattern, "/") {
+			target = strings.Join(elems[:i+1], "/")
+		} else {
+			target = elems[i]
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDep reports whether path relPath of vendor dir matches any of patterns
+// of its dependency.
+func matchDep(deps []depEntry, relPath string, patterns func(d depEntry) []string) bool {
+	d, rel, ok := depOwner(deps, relPath)
+	if !ok {
+		return false
+	}
+	for _, pattern := range patterns(d) {
+		if matchPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// isKept reports whether path relPath of vendor dir is kept by keep patterns of
+// its dependency.
+func isKept(deps []depEntry, relPath string) bool {
+	return matchDep(deps, relPath, func(d depEntry) []string { return d.keep })
+}
+
+// isDropped reports whether path relPath of vendor dir is removed by drop
+// patterns of its dependency.
+func isDropped(deps []depEntry, relPath string) bool {
+	return matchDep(deps, relPath, func(d depEntry) []string { return d.drop })
+}
+
+// leadsToKept reports whether directory relPath of vendor dir contains paths
+// kept by keep patterns with slashes.
+func leadsToKept(deps []depEntry, relPath string) bool {
+	d, rel, ok := depOwner(deps, relPath)
+	if !ok {
+		return false
+	}
+	for _, pattern := range d.keep {
+		if strings.HasPrefix(strings.Trim(pattern, "/"), rel+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedPaths returns files matched by //go:embed patterns of pkgs and
+// directories which contain them. Files in matched directories with names
+// starting with . or _ are skipped unless the pattern starts with "all:".
+func embeddedPaths(pkgs []*build.Package) map[string]bool {
+	embedded := make(map[string]bool)
+	add := func(dir, path string) {
+		for p := path; p != dir && strings.HasPrefix(p, dir) && !embedded[p]; p = filepath.Dir(p) {
+			embedded[p] = true
+		}
+	}
+	for _, pkg := range pkgs {
+		for _, pattern := range pkg.EmbedPatterns {
+			all := strings.HasPrefix(pattern, "all:")
+			pattern = strings.TrimPrefix(pattern, "all:")
+			matches, err := filepath.Glob(filepath.Join(pkg.Dir, filepath.FromSlash(pattern)))
+			if err != nil {
+				continue
+			}
+			for _, m := range matches {
+				filepath.Walk(m, func(path string, i os.FileInfo, err error) error {
+					if err != nil {
+						return nil
+					}
+					name := i.Name()
+					if path != m && !all && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+						if i.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+					if !i.IsDir() {
+						add(pkg.Dir, path)
+					}
+					return nil
+				})
+			}
+		}
+	}
+	return embedded
+}
+
+// cleanVendor removes files from unused packages and non-go files. Paths
+// matching keep patterns of deps are not removed, paths matching drop patterns
+// are always removed. Files embedded by //go:embed directives of realDeps are
+// kept.
+func cleanVendor(vendorDir string, realDeps []*build.Package, deps []depEntry) error {
+	embedded := embeddedPaths(realDeps)
 	realPaths := make(map[string]bool)
 	ignoredGoFiles := []string{}
+	var ignoredFiles []string
 	for _, pkg := range realDeps {
 		realPaths[pkg.Dir] = true
 		for _, f := range pkg.IgnoredGoFiles {
 			ignoredGoFiles = append(ignoredGoFiles, filepath.Join(pkg.Dir, f))
 		}
+		for _, f := range pkg.IgnoredOtherFiles {
+			ignoredFiles = append(ignoredFiles, filepath.Join(pkg.Dir, f))
+		}
 	}
 	var paths []string
 	err := filepath.Walk(vendorDir, func(path string, i os.FileInfo, err error) error {
@@ -95,15 +222,31 @@ func cleanVendor(vendorDir string, realDeps []*build.Package) error {
 		if err != nil {
 			return err
 		}
-		if cleanWhitelist.matchString(relPath) {
+		if isDropped(deps, relPath) {
+			cleanReport.add(relPath, ruleDrop)
+			return os.RemoveAll(path)
+		}
+		if cleanWhitelist.matchString(relPath) || isKept(deps, relPath) || embedded[path] {
+			return nil
+		}
+		if i.IsDir() && leadsToKept(deps, relPath) {
+			if !realPaths[path] {
+				paths = append(paths, path)
+			}
 			return nil
 		}
 
-		if strings.HasPrefix(i.Name(), ".") || strings.HasPrefix(i.Name(), "_") {
+		if strings.HasPrefix(i.Name(), ".") {
+			cleanReport.add(relPath, ruleDotfile)
+			return os.RemoveAll(path)
+		}
+		if strings.HasPrefix(i.Name(), "_") {
+			cleanReport.add(relPath, ruleUnderscore)
 			return os.RemoveAll(path)
 		}
 		if i.IsDir() {
 			if i.Name() == "testdata" {
+				cleanReport.add(relPath, ruleTestdata)
 				return os.RemoveAll(path)
 			}
 			if isInterestingDir(path) {
@@ -121,12 +264,28 @@ func cleanVendor(vendorDir string, realDeps []*build.Package) error {
 			return nil
 		}
 		// remove files from non-deps, non-go files and test files
-		if !realPaths[filepath.Dir(path)] || !isGoFile(path) || strings.HasSuffix(path, "_test.go") {
+		if !realPaths[filepath.Dir(path)] {
+			cleanReport.add(relPath, ruleUnused)
+			return os.Remove(path)
+		}
+		if !isGoFile(path) {
+			cleanReport.add(relPath, ruleNonGo)
+			return os.Remove(path)
+		}
+		if strings.HasSuffix(path, "_test.go") {
+			cleanReport.add(relPath, ruleTestFile)
 			return os.Remove(path)
 		}
 		// remove ignored go files
 		for _, f := range ignoredGoFiles {
 			if f == path {
+				cleanReport.add(relPath, ruleIgnoredGo)
+				return os.Remove(path)
+			}
+		}
+		for _, f := range ignoredFiles {
+			if f == path {
+				cleanReport.add(relPath, ruleIgnored)
 				return os.Remove(path)
 			}
 		}
@@ -151,7 +310,7 @@ func cleanVendor(vendorDir string, realDeps []*build.Package) error {
 			if err != nil {
 				return err
 			}
-			if cleanWhitelist.matchString(relPath) || fi.IsDir() || isGoFile(fi.Name()) {
+			if cleanWhitelist.matchString(relPath) || isKept(deps, relPath) || fi.IsDir() || isGoFile(fi.Name()) {
 				onlyNonGoFile = false
 				break
 			}
@@ -160,6 +319,11 @@ func cleanVendor(vendorDir string, realDeps []*build.Package) error {
 			continue
 		}
 		// remove all directories if they're not in dependency paths
+		relPath, err := filepath.Rel(vendorDir, p)
+		if err != nil {
+			return err
+		}
+		cleanReport.add(relPath, ruleUnused)
 		if err := os.RemoveAll(p); err != nil {
 			return err
 		}
@@ -168,7 +332,11 @@ func cleanVendor(vendorDir string, realDeps []*build.Package) error {
 }
 
 func cleanVCS(v *godl.VCS) error {
-	if err := os.RemoveAll(filepath.Join(v.Root, "."+v.Type)); err != nil {
+	vcsDir := filepath.Join(v.Root, "."+v.Type)
+	if _, err := os.Stat(vcsDir); err == nil {
+		cleanReport.add(filepath.Join(v.ImportPath, "."+v.Type), ruleVCS)
+	}
+	if err := os.RemoveAll(vcsDir); err != nil {
 		return err
 	}
 	return filepath.Walk(v.Root, func(path string, i os.FileInfo, err error) error {
@@ -183,6 +351,9 @@ func cleanVCS(v *godl.VCS) error {
 		}
 		name := i.Name()
 		if name == "vendor" || name == "Godeps" || name == "_vendor" {
+			if rel, err := filepath.Rel(v.Root, path); err == nil {
+				cleanReport.add(filepath.Join(v.ImportPath, rel), ruleNestedVendor)
+			}
 			if err := os.RemoveAll(path); err != nil {
 				return err
 			}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/LK4D4/vndr/build"
 )
 
 func TestLicenseFilesRegexp(t *testing.T) {
@@ -21,3 +28,202 @@ func TestLicenseFilesRegexp(t *testing.T) {
 		}
 	}
 }
+
+func TestIsKept(t *testing.T) {
+	deps := []depEntry{
+		{importPath: "github.com/a/a", keep: []string{"testdata", "*.json", "docs/api"}},
+		{importPath: "github.com/a/a/sub", keep: []string{"fixtures"}},
+		{importPath: "github.com/b/b"},
+	}
+	cases := map[string]bool{
+		"github.com/a/a":                      false,
+		"github.com/a/a/testdata":             true,
+		"github.com/a/a/pkg/testdata/x.txt":   true,
+		"github.com/a/a/pkg/schema.json":      true,
+		"github.com/a/a/docs/api/index.md":    true,
+		"github.com/a/a/pkg/docs/api":         false,
+		"github.com/a/a/sub/fixtures/x":       true,
+		"github.com/a/a/sub/testdata/x":       false,
+		"github.com/a/a/README.md":            false,
+		"github.com/b/b/testdata":             false,
+		"github.com/c/c/testdata/schema.json": false,
+	}
+	for p, expected := range cases {
+		if kept := isKept(deps, p); kept != expected {
+			t.Fatalf("isKept(%q): expected %v, got %v", p, expected, kept)
+		}
+	}
+}
+
+func TestIsDropped(t *testing.T) {
+	deps := []depEntry{
+		{importPath: "github.com/a/a", keep: []string{"testdata"}, drop: []string{"testdata/large", "examples"}},
+	}
+	cases := map[string]bool{
+		"github.com/a/a/testdata/small":       false,
+		"github.com/a/a/testdata/large/x.bin": true,
+		"github.com/a/a/examples":             true,
+		"github.com/a/a/cmd/examples/main.go": true,
+		"github.com/b/b/examples":             false,
+	}
+	for p, expected := range cases {
+		if dropped := isDropped(deps, p); dropped != expected {
+			t.Fatalf("isDropped(%q): expected %v, got %v", p, expected, dropped)
+		}
+	}
+}
+
+func TestCleanVendorReport(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	writeFiles(t, vd, map[string]string{
+		"github.com/a/a/a.go":              "",
+		"github.com/a/a/a_test.go":         "",
+		"github.com/a/a/ignored.go":        "",
+		"github.com/a/a/notes.txt":         "",
+		"github.com/a/a/LICENSE":           "",
+		"github.com/a/a/.travis.yml":       "",
+		"github.com/a/a/_examples/main.go": "",
+		"github.com/a/a/testdata/x.json":   "",
+		"github.com/a/a/large/data.go":     "",
+		"github.com/a/a/unused/unused.go":  "",
+	})
+	pkgs := []*build.Package{{Dir: filepath.Join(vd, "github.com/a/a"), IgnoredGoFiles: []string{"ignored.go"}}}
+	deps := []depEntry{{importPath: "github.com/a/a", drop: []string{"large"}}}
+	cleanReport = &removalLog{}
+	defer func() { cleanReport = nil }()
+	if err := cleanVendor(vd, pkgs, deps); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"github.com/a/a/a_test.go":   ruleTestFile,
+		"github.com/a/a/ignored.go":  ruleIgnoredGo,
+		"github.com/a/a/notes.txt":   ruleNonGo,
+		"github.com/a/a/.travis.yml": ruleDotfile,
+		"github.com/a/a/_examples":   ruleUnderscore,
+		"github.com/a/a/testdata":    ruleTestdata,
+		"github.com/a/a/large":       ruleDrop,
+		"github.com/a/a/unused":      ruleUnused,
+		// files of unused package are removed before its dir
+		"github.com/a/a/unused/unused.go": ruleUnused,
+	}
+	removed := make(map[string]string)
+	for _, r := range cleanReport.removed {
+		if _, ok := removed[r.Path]; ok {
+			t.Fatalf("%s is reported twice", r.Path)
+		}
+		removed[r.Path] = r.Rule
+	}
+	for p, rule := range expected {
+		if removed[p] != rule {
+			t.Fatalf("%s: expected rule %q, got %q", p, rule, removed[p])
+		}
+	}
+	if len(removed) != len(expected) {
+		t.Fatalf("expected %d removals, got %v", len(expected), cleanReport.removed)
+	}
+	for _, f := range []string{"github.com/a/a/a.go", "github.com/a/a/LICENSE"} {
+		if _, err := os.Stat(filepath.Join(vd, f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCleanVendorEmbed(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-embed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	files := map[string]string{
+		"github.com/a/a/a.go":             "package a\n\nimport \"embed\"\n\n//go:embed templates/*.tmpl \"static\"\nvar fs embed.FS\n\n//go:embed all:testdata\nvar data embed.FS\n",
+		"github.com/a/a/a_test.go":        "package a\n\nimport \"embed\"\n\n//go:embed other\nvar fs embed.FS\n",
+		"github.com/a/a/templates/a.tmpl": "",
+		"github.com/a/a/templates/b.txt":  "",
+		"github.com/a/a/static/app.js":    "",
+		"github.com/a/a/static/.hidden":   "",
+		"github.com/a/a/static/_x/y.js":   "",
+		"github.com/a/a/testdata/.keep":   "",
+		"github.com/a/a/other/x.txt":      "",
+	}
+	writeFiles(t, vd, files)
+	pkg, err := ctx.ImportDir(filepath.Join(vd, "github.com/a/a"), build.ImportMode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"all:testdata", "static", "templates/*.tmpl"}
+	if !reflect.DeepEqual(pkg.EmbedPatterns, expected) {
+		t.Fatalf("expected patterns %v, got %v", expected, pkg.EmbedPatterns)
+	}
+	if err := cleanVendor(vd, []*build.Package{pkg}, nil); err != nil {
+		t.Fatal(err)
+	}
+	kept := map[string]bool{
+		"github.com/a/a/a.go":             true,
+		"github.com/a/a/templates/a.tmpl": true,
+		"github.com/a/a/static/app.js":    true,
+		"github.com/a/a/testdata/.keep":   true,
+	}
+	for f := range files {
+		_, err := os.Stat(filepath.Join(vd, f))
+		if exists := err == nil; exists != kept[f] {
+			t.Fatalf("%s: expected kept %v, got %v", f, kept[f], exists)
+		}
+	}
+}
+
+func TestCleanVendorInvalidConstraint(t *testing.T) {
+	gp, err := ioutil.TempDir("", "vndr-constraint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gp)
+	wd := filepath.Join(gp, "src", "example.com", "p")
+	vd := filepath.Join(wd, "vendor")
+	files := map[string]string{
+		"main.go":                      "package main\n\nimport _ \"github.com/a/a\"\n",
+		"vendor/github.com/a/a/a.go":   "package a\n\nimport _ \"github.com/b/b\"\n",
+		"vendor/github.com/a/a/bad.go": "//go:build linux\n//go:build darwin\n\npackage a\n",
+		"vendor/github.com/b/b/b.go":   "package b\n",
+		"vendor/github.com/c/c/c.go":   "package c\n",
+	}
+	writeFiles(t, wd, files)
+	defer func(gopath string) { ctx.GOPATH = gopath }(ctx.GOPATH)
+	ctx.GOPATH = gp
+	initPkg, err := ctx.ImportDir(wd, build.ImportMode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, err := collectAllDeps(wd, nil, nil, initPkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, pkg := range deps {
+		dir, err := filepath.Rel(wd, pkg.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dir == "vendor/github.com/a/a" && !reflect.DeepEqual(pkg.InvalidGoFiles, []string{"bad.go"}) {
+			t.Fatalf("expected invalid files [bad.go], got %v", pkg.InvalidGoFiles)
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	expected := []string{".", "vendor/github.com/a/a", "vendor/github.com/b/b"}
+	if !reflect.DeepEqual(dirs, expected) {
+		t.Fatalf("expected packages %v, got %v", expected, dirs)
+	}
+	if err := cleanVendor(vd, deps, nil); err != nil {
+		t.Fatal(err)
+	}
+	for f := range files {
+		_, err := os.Stat(filepath.Join(wd, f))
+		if exists := err == nil; exists != (f != "vendor/github.com/c/c/c.go") {
+			t.Fatalf("%s: unexpected existence %v", f, exists)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -16,46 +15,83 @@ type depEntry struct {
 	importPath string
 	rev        string
 	repoPath   string
+
+	// optional attributes written as key=value after repository
+	vcs         string   // version control system of repository, i.e. "hg"
+	keep        []string // patterns of paths which cleaning keeps
+	drop        []string // patterns of paths which cleaning always removes
+	subpackages []string // packages which are vendored even if unused
+	license     string   // license of dependency without license files
 }
 
 func (d depEntry) String() string {
-	return fmt.Sprintf("%s %s\n", d.importPath, d.rev)
+	s := d.importPath + " " + d.rev
+	if d.repoPath != "" {
+		s += " " + d.repoPath
+	}
+	for _, a := range d.attrs() {
+		s += " " + a
+	}
+	return s + "\n"
 }
 
-func parseDeps(r io.Reader) ([]depEntry, error) {
-	var deps []depEntry
-	s := bufio.NewScanner(r)
-	for s.Scan() {
-		ln := strings.TrimSpace(s.Text())
-		if strings.HasPrefix(ln, "#") || ln == "" {
-			continue
-		}
-		cidx := strings.Index(ln, "#")
-		if cidx > 0 {
-			ln = ln[:cidx]
-		}
-		ln = strings.TrimSpace(ln)
-		parts := strings.Fields(ln)
-		if len(parts) != 2 && len(parts) != 3 {
-			return nil, fmt.Errorf("invalid config format: %s", ln)
-		}
-		d := depEntry{
-			importPath: parts[0],
-			rev:        parts[1],
-		}
-		if len(parts) == 3 {
-			d.repoPath = parts[2]
-		}
-		deps = append(deps, d)
+// attrs returns attributes of d in key=value form.
+func (d depEntry) attrs() []string {
+	var attrs []string
+	if d.vcs != "" {
+		attrs = append(attrs, "vcs="+d.vcs)
+	}
+	if len(d.keep) > 0 {
+		attrs = append(attrs, "keep="+strings.Join(d.keep, ","))
+	}
+	if len(d.drop) > 0 {
+		attrs = append(attrs, "drop="+strings.Join(d.drop, ","))
+	}
+	if len(d.subpackages) > 0 {
+		attrs = append(attrs, "subpackages="+strings.Join(d.subpackages, ","))
+	}
+	if d.license != "" {
+		attrs = append(attrs, "license="+d.license)
+	}
+	return attrs
+}
+
+// setAttr sets attribute of d from key=value string.
+func (d *depEntry) setAttr(kv string) error {
+	i := strings.Index(kv, "=")
+	key, value := kv[:i], kv[i+1:]
+	if value == "" {
+		return fmt.Errorf("empty value of attribute %s", key)
 	}
-	if err := s.Err(); err != nil {
+	switch key {
+	case "vcs":
+		d.vcs = value
+	case "keep":
+		d.keep = strings.Split(value, ",")
+	case "drop":
+		d.drop = strings.Split(value, ",")
+	case "subpackages":
+		d.subpackages = strings.Split(value, ",")
+	case "license":
+		d.license = value
+	default:
+		return fmt.Errorf("unknown attribute %s", key)
+	}
+	return nil
+}
+
+func parseDeps(r io.Reader) ([]depEntry, error) {
+	c, err := parseConfig(r)
+	if err != nil {
 		return nil, err
 	}
-	return deps, nil
+	return c.deps(), nil
 }
 
-func cloneAll(vd string, ds []depEntry) error {
+func cloneAll(vd string, ds []depEntry) ([]lockEntry, error) {
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var locked []lockEntry
 	errCh := make(chan error, len(ds))
 	limit := make(chan struct{}, 16)
 	for _, d := range ds {
@@ -69,7 +105,11 @@ func cloneAll(vd string, ds []depEntry) error {
 				} else {
 					log.Printf("\tClone %s, revision %s, attempt %d/20", d.importPath, d.rev, i+1)
 				}
-				if err = cloneDep(vd, d); err == nil {
+				var l lockEntry
+				if l, err = cloneDep(vd, d); err == nil {
+					mu.Lock()
+					locked = append(locked, l)
+					mu.Unlock()
 					errCh <- nil
 					wg.Done()
 					<-limit
@@ -93,15 +133,126 @@ func cloneAll(vd string, ds []depEntry) error {
 		}
 	}
 	if len(errs) == 0 {
-		return nil
+		return locked, nil
+	}
+	return nil, fmt.Errorf("Errors on clone:\n%s", strings.Join(errs, "\n"))
+}
+
+// lockedRepos are entries of existing lock file. In offline mode repositories
+// from them are used instead of analyzing import paths.
+var lockedRepos = map[string]lockEntry{}
+
+func download(vd string, d depEntry) (*godl.VCS, error) {
+	if l, ok := lockedRepos[d.importPath]; ok && offline && (d.repoPath == "" || d.repoPath == l.repo) {
+		return godl.DownloadRepo(d.importPath, l.vcs, l.repo, vd, d.rev)
+	}
+	if d.vcs != "" {
+		return godl.DownloadRepo(d.importPath, d.vcs, d.repoPath, vd, d.rev)
+	}
+	return godl.Download(d.importPath, d.repoPath, vd, d.rev)
+}
+
+func resolve(d depEntry) (*godl.VCS, error) {
+	if l, ok := lockedRepos[d.importPath]; ok && offline && (d.repoPath == "" || d.repoPath == l.repo) {
+		return &godl.VCS{ImportPath: d.importPath, Type: l.vcs, Repo: l.repo}, nil
+	}
+	if d.vcs != "" {
+		return &godl.VCS{ImportPath: d.importPath, Type: d.vcs, Repo: d.repoPath}, nil
+	}
+	return godl.Resolve(d.importPath, d.repoPath)
+}
+
+// branchPrefix marks revisions which are branch names like branch:release-1.4.
+const branchPrefix = "branch:"
+
+// checkoutRev returns revision which version control system understands.
+func checkoutRev(rev string) string {
+	return strings.TrimPrefix(rev, branchPrefix)
+}
+
+// resolveRev resolves tag or branch revision of d to commit by references of
+// remote repository. Revisions which are not found among references are
+// returned as is, except branches. In offline mode commit from the lock is
+// used.
+func resolveRev(d depEntry) (string, error) {
+	commit, _, err := resolveRef(d)
+	return commit, err
+}
+
+// resolveRef is like resolveRev, but also returns the reference which
+// revision was resolved by, i.e. "refs/tags/v1.0.0". The reference is empty
+// if revision wasn't resolved by references.
+func resolveRef(d depEntry) (string, string, error) {
+	// shorter hex revisions can be names of tags or branches, git never
+	// treats full hashes as names
+	if len(d.rev) == 40 && godl.IsCommit(d.rev) {
+		return d.rev, "", nil
+	}
+	if offline {
+		if l, ok := lockedRepos[d.importPath]; ok && l.rev == d.rev && l.commit != "" {
+			return l.commit, "", nil
+		}
+		return checkoutRev(d.rev), "", nil
+	}
+	v, err := resolve(d)
+	if err != nil {
+		return "", "", err
 	}
-	return fmt.Errorf("Errors on clone:\n%s", strings.Join(errs, "\n"))
+	if v.Type != "git" {
+		return checkoutRev(d.rev), "", nil
+	}
+	refs, err := godl.RemoteRefs(v.Type, v.Repo)
+	if err != nil {
+		return "", "", err
+	}
+	if branch := strings.TrimPrefix(d.rev, branchPrefix); branch != d.rev {
+		ref := "refs/heads/" + branch
+		commit, ok := refs[ref]
+		if !ok {
+			return "", "", fmt.Errorf("branch %s is not found in %s", branch, v.Repo)
+		}
+		return commit, ref, nil
+	}
+	for _, ref := range []string{"refs/tags/" + d.rev, "refs/heads/" + d.rev} {
+		if commit, ok := refs[ref]; ok {
+			return commit, ref, nil
+		}
+	}
+	return d.rev, "", nil
 }
 
-func cloneDep(vd string, d depEntry) error {
-	vcs, err := godl.Download(d.importPath, d.repoPath, vd, d.rev)
+func cloneDep(vd string, d depEntry) (lockEntry, error) {
+	commit, ref, err := resolveRef(d)
 	if err != nil {
-		return fmt.Errorf("%s: %v", d.importPath, err)
+		return lockEntry{}, fmt.Errorf("%s: %v", d.importPath, err)
+	}
+	if commit != d.rev && godl.IsCommit(commit) {
+		log.Printf("\tResolved %s %s to %s", d.importPath, d.rev, commit)
+		// only tags are not expected to move
+		l, ok := lockedRepos[d.importPath]
+		if ok && l.rev == d.rev && l.commit != commit && strings.HasPrefix(ref, "refs/tags/") {
+			Warnf("%s: tag %s moved from %s to %s", d.importPath, d.rev, l.commit, commit)
+		}
+	}
+	rd := d
+	rd.rev = commit
+	vcs, err := download(vd, rd)
+	if err != nil {
+		return lockEntry{}, fmt.Errorf("%s: %v", d.importPath, err)
+	}
+	commit, err = getRev(vcs)
+	if err != nil {
+		return lockEntry{}, fmt.Errorf("%s: %v", d.importPath, err)
+	}
+	if err := cleanVCS(vcs); err != nil {
+		return lockEntry{}, err
 	}
-	return cleanVCS(vcs)
+	return lockEntry{
+		importPath: d.importPath,
+		rev:        d.rev,
+		commit:     commit,
+		vcs:        vcs.Type,
+		repo:       vcs.Repo,
+		clean:      cleanInputs(d),
+	}, nil
 }
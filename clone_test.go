@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LK4D4/vndr/godl"
+)
+
+func TestResolveRefHexTag(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "vndr-resolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	repo := filepath.Join(tmp, "repo")
+	git := gitRepo(t, repo)
+	var commits []string
+	for _, name := range []string{"a", "b"} {
+		writeFiles(t, repo, map[string]string{name: name})
+		git("add", name)
+		git("commit", "-q", "-m", name)
+		commits = append(commits, git("rev-parse", "HEAD"))
+	}
+	git("tag", "1234567", commits[0])
+
+	defer func(dir string) { godl.CacheDir = dir }(godl.CacheDir)
+	godl.CacheDir = filepath.Join(tmp, "cache")
+	cases := []struct {
+		rev    string
+		commit string
+		ref    string
+	}{
+		{"1234567", commits[0], "refs/tags/1234567"},
+		{commits[1][:7], commits[1][:7], ""},
+		{commits[1], commits[1], ""},
+	}
+	for _, c := range cases {
+		d := depEntry{importPath: "example.com/repo", rev: c.rev, repoPath: repo, vcs: "git"}
+		commit, ref, err := resolveRef(d)
+		if err != nil {
+			t.Fatalf("%s: %v", c.rev, err)
+		}
+		if commit != c.commit || ref != c.ref {
+			t.Fatalf("%s: expected %s %q, got %s %q", c.rev, c.commit, c.ref, commit, ref)
+		}
+	}
+
+	// tag which looks like a commit hash is moved
+	git("tag", "-f", "1234567", commits[1])
+	defer func(locked map[string]lockEntry) { lockedRepos = locked }(lockedRepos)
+	lockedRepos = map[string]lockEntry{
+		"example.com/repo": {importPath: "example.com/repo", rev: "1234567", commit: commits[0]},
+	}
+	warns := len(Warns())
+	d := depEntry{importPath: "example.com/repo", rev: "1234567", repoPath: repo, vcs: "git"}
+	l, err := cloneDep(filepath.Join(tmp, "vendor"), d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.commit != commits[1] {
+		t.Fatalf("expected commit %s, got %s", commits[1], l.commit)
+	}
+	expected := "example.com/repo: tag 1234567 moved from " + commits[0] + " to " + commits[1]
+	if w := Warns()[warns:]; len(w) != 1 || w[0] != expected {
+		t.Fatalf("expected warning %q, got %q", expected, w)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// attrRe matches optional key=value attributes of dependency.
+var attrRe = regexp.MustCompile(`^[a-z]+=`)
+
+// configLine is a line of config file. Lines keep their original text, so
+// comments, blank lines and alignment survive rewriting of the config.
+type configLine struct {
+	text string
+	dep  *depEntry // nil for comments and blank lines
+}
+
+// config is a parsed config file, which is written back as it was read except
+// for modified dependencies.
+type config struct {
+	lines []configLine
+}
+
+func parseConfig(r io.Reader) (*config, error) {
+	c := &config{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := configLine{text: s.Text()}
+		ln := strings.TrimSpace(l.text)
+		if strings.HasPrefix(ln, "#") || ln == "" {
+			c.lines = append(c.lines, l)
+			continue
+		}
+		cidx := strings.Index(ln, "#")
+		if cidx > 0 {
+			ln = ln[:cidx]
+		}
+		ln = strings.TrimSpace(ln)
+		parts := strings.Fields(ln)
+		var attrs []string
+		for len(parts) > 2 && attrRe.MatchString(parts[len(parts)-1]) {
+			attrs = append([]string{parts[len(parts)-1]}, attrs...)
+			parts = parts[:len(parts)-1]
+		}
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("invalid config format: %s", ln)
+		}
+		d := depEntry{
+			importPath: parts[0],
+			rev:        parts[1],
+		}
+		if len(parts) == 3 {
+			d.repoPath = parts[2]
+		}
+		for _, a := range attrs {
+			if err := d.setAttr(a); err != nil {
+				return nil, fmt.Errorf("invalid config line %s: %v", ln, err)
+			}
+		}
+		if d.vcs != "" && d.repoPath == "" {
+			return nil, fmt.Errorf("invalid config line %s: vcs attribute requires repository", ln)
+		}
+		l.dep = &d
+		c.lines = append(c.lines, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newConfig returns config with deps in default format.
+func newConfig(deps []depEntry) *config {
+	c := &config{}
+	for _, d := range deps {
+		c.add(d)
+	}
+	return c
+}
+
+func readConfig(file string) (*config, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open config file: %v", err)
+	}
+	defer f.Close()
+	c, err := parseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse config: %v", err)
+	}
+	return c, nil
+}
+
+// directivePrefix starts comment lines of config which set options of vndr,
+// like "#vndr:ignore-tags gofuzz integration".
+const directivePrefix = "#vndr:"
+
+// directive returns values of all directives name in config. Values are
+// separated by spaces or commas.
+func (c *config) directive(name string) []string {
+	var values []string
+	for _, l := range c.lines {
+		ln := strings.TrimSpace(l.text)
+		if l.dep != nil || !strings.HasPrefix(ln, directivePrefix) {
+			continue
+		}
+		f := strings.Fields(strings.TrimPrefix(ln, directivePrefix))
+		if len(f) == 0 || f[0] != name {
+			continue
+		}
+		for _, v := range f[1:] {
+			values = append(values, splitList(v)...)
+		}
+	}
+	return values
+}
+
+// splitList splits comma separated list s, empty items are skipped.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (c *config) deps() []depEntry {
+	var deps []depEntry
+	for _, l := range c.lines {
+		if l.dep != nil {
+			deps = append(deps, *l.dep)
+		}
+	}
+	return deps
+}
+
+// add appends d to the end of config.
+func (c *config) add(d depEntry) {
+	c.lines = append(c.lines, configLine{text: strings.TrimSuffix(d.String(), "\n"), dep: &d})
+}
+
+// set replaces the first dependency with import path imp by d. It returns
+// false if there is no such dependency.
+func (c *config) set(imp string, d depEntry) bool {
+	for i, l := range c.lines {
+		if l.dep != nil && l.dep.importPath == imp {
+			c.lines[i] = l.with(d)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *config) bytes() []byte {
+	var b bytes.Buffer
+	for _, l := range c.lines {
+		b.WriteString(l.text + "\n")
+	}
+	return b.Bytes()
+}
+
+func (c *config) write(file string) error {
+	return ioutil.WriteFile(file, c.bytes(), os.FileMode(0666))
+}
+
+// with returns line with dependency d, only changed columns of the line are
+// rewritten.
+func (l configLine) with(d depEntry) configLine {
+	text := l.text
+	old := *l.dep
+	if strings.Join(d.attrs(), " ") != strings.Join(old.attrs(), " ") {
+		// attributes are rewritten in default format, only comment is kept
+		text = strings.TrimSuffix(d.String(), "\n")
+		if i := strings.Index(l.text, "#"); i >= 0 {
+			text += " " + l.text[i:]
+		}
+		return configLine{text: text, dep: &d}
+	}
+	switch {
+	case old.repoPath == "" && d.repoPath != "":
+		text = insertField(text, 2, d.repoPath)
+	case d.repoPath != old.repoPath:
+		text = setField(text, 2, d.repoPath)
+	}
+	if d.rev != old.rev {
+		text = setField(text, 1, d.rev)
+	}
+	if d.importPath != old.importPath {
+		text = setField(text, 0, d.importPath)
+	}
+	return configLine{text: text, dep: &d}
+}
+
+// fieldSpans returns positions of columns of config line ln.
+func fieldSpans(ln string) [][2]int {
+	content := ln
+	if i := strings.Index(ln, "#"); i >= 0 {
+		content = ln[:i]
+	}
+	var spans [][2]int
+	for i := 0; i < len(content); {
+		if strings.ContainsRune(" \t\r", rune(content[i])) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(content) && !strings.ContainsRune(" \t\r", rune(content[j])) {
+			j++
+		}
+		spans = append(spans, [2]int{i, j})
+		i = j
+	}
+	return spans
+}
+
+// insertField inserts value as n-th column of config line ln.
+func insertField(ln string, n int, value string) string {
+	spans := fieldSpans(ln)
+	if n == 0 || n > len(spans) {
+		return ln
+	}
+	end := spans[n-1][1]
+	return ln[:end] + " " + value + ln[end:]
+}
+
+// setField replaces n-th column of config line ln by value, empty value
+// removes the column. If columns are aligned by spaces, the next column stays
+// in place.
+func setField(ln string, n int, value string) string {
+	spans := fieldSpans(ln)
+	switch {
+	case n >= len(spans):
+		return ln
+	case value == "":
+		return ln[:spans[n-1][1]] + ln[spans[n][1]:]
+	}
+	start, end := spans[n][0], spans[n][1]
+	tail := ln[end:]
+	rest := strings.TrimLeft(tail, " ")
+	// single space separates columns which are not aligned
+	if spaces := len(tail) - len(rest); spaces > 1 && rest != "" && !strings.ContainsAny(rest[:1], "\t\r") {
+		pad := spaces + (end - start) - len(value)
+		if pad < 1 {
+			pad = 1
+		}
+		tail = strings.Repeat(" ", pad) + rest
+	}
+	return ln[:start] + value + tail
+}
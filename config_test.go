@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	content := `# networking
+github.com/a/a      v1.9.0   https://github.com/x/a.git # fork
+
+github.com/b/b v1.0.0 # comment
+	github.com/c/c	v1.9.0
+`
+	c, err := parseConfig(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(c.bytes()); out != content {
+		t.Fatalf("expected %q, got %q", content, out)
+	}
+	if len(c.deps()) != 3 {
+		t.Fatalf("expected 3 dependencies, got %v", c.deps())
+	}
+}
+
+func TestConfigSet(t *testing.T) {
+	cases := []struct {
+		in  string
+		d   depEntry
+		out string
+	}{
+		{"github.com/a/a v1.9.0", depEntry{importPath: "github.com/a/a", rev: "v1.10.0"}, "github.com/a/a v1.10.0"},
+		{"github.com/a/a      v1.9.0   https://github.com/x/a.git # fork", depEntry{importPath: "github.com/a/a", rev: "v1.10.0", repoPath: "https://github.com/x/a.git"}, "github.com/a/a      v1.10.0  https://github.com/x/a.git # fork"},
+		{"github.com/a/a v1.0.0 # comment", depEntry{importPath: "github.com/a/a", rev: "645ef00459ed84a119197bfb8d8205042c6df63d"}, "github.com/a/a 645ef00459ed84a119197bfb8d8205042c6df63d # comment"},
+		{"github.com/a/a\tv1.9.0\thttps://github.com/x/a.git", depEntry{importPath: "github.com/a/a", rev: "v1.10.0", repoPath: "https://github.com/x/a.git"}, "github.com/a/a\tv1.10.0\thttps://github.com/x/a.git"},
+		{"github.com/a/a/sub branch", depEntry{importPath: "github.com/a/a", rev: "branch"}, "github.com/a/a branch"},
+		{"github.com/a/a/sub      v1 # comment", depEntry{importPath: "github.com/a/a", rev: "v1"}, "github.com/a/a          v1 # comment"},
+		{"github.com/a/a v1 # comment", depEntry{importPath: "github.com/a/a", rev: "v1", repoPath: "https://github.com/x/a.git"}, "github.com/a/a v1 https://github.com/x/a.git # comment"},
+		{"github.com/a/a v1 https://github.com/x/a.git # comment", depEntry{importPath: "github.com/a/a", rev: "v1"}, "github.com/a/a v1 # comment"},
+		{"github.com/a/a v1 keep=testdata", depEntry{importPath: "github.com/a/a", rev: "v2", keep: []string{"testdata"}}, "github.com/a/a v2 keep=testdata"},
+		{"github.com/a/a v1 keep=testdata", depEntry{importPath: "github.com/a/a", rev: "v1", repoPath: "https://github.com/x/a.git", keep: []string{"testdata"}}, "github.com/a/a v1 https://github.com/x/a.git keep=testdata"},
+		{"github.com/a/a   v1 # comment", depEntry{importPath: "github.com/a/a", rev: "v1", license: "MIT"}, "github.com/a/a v1 license=MIT # comment"},
+	}
+	for _, c := range cases {
+		cfg, err := parseConfig(strings.NewReader(c.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.set(cfg.deps()[0].importPath, c.d) {
+			t.Fatalf("%q: dependency is not found", c.in)
+		}
+		if out := strings.TrimSuffix(string(cfg.bytes()), "\n"); out != c.out {
+			t.Fatalf("expected %q, got %q", c.out, out)
+		}
+	}
+}
+
+func TestConfigAttributes(t *testing.T) {
+	content := `bitbucket.org/a/a v1 https://bitbucket.org/a/a vcs=hg license=Apache-2.0
+github.com/b/b v1.0.0 keep=testdata,*.json subpackages=.,cmd/b # comment
+`
+	deps, err := parseDeps(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []depEntry{
+		{importPath: "bitbucket.org/a/a", rev: "v1", repoPath: "https://bitbucket.org/a/a", vcs: "hg", license: "Apache-2.0"},
+		{importPath: "github.com/b/b", rev: "v1.0.0", keep: []string{"testdata", "*.json"}, subpackages: []string{".", "cmd/b"}},
+	}
+	if len(deps) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, deps)
+	}
+	for i, d := range deps {
+		if d.String() != expected[i].String() {
+			t.Fatalf("expected %q, got %q", expected[i].String(), d.String())
+		}
+	}
+	for _, ln := range []string{
+		"github.com/a/a v1 foo=bar",
+		"github.com/a/a v1 keep=",
+		"github.com/a/a v1 vcs=hg",
+		"github.com/a/a v1 https://github.com/a/a extra keep=x",
+	} {
+		if _, err := parseDeps(strings.NewReader(ln)); err == nil {
+			t.Fatalf("%q: expected error", ln)
+		}
+	}
+}
+
+func TestConfigDirective(t *testing.T) {
+	content := `#vndr:ignore-tags gofuzz,integration
+# vndr:ignore-tags comment
+github.com/a/a v1 # vndr:ignore-tags comment
+#vndr:ignore-tags tools
+#vndr:other value
+`
+	c, err := parseConfig(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := c.directive("ignore-tags")
+	expected := []string{"gofuzz", "integration", "tools"}
+	if strings.Join(tags, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/LK4D4/vndr/godl"
+	"github.com/LK4D4/vndr/versioned"
+)
+
+// sameRev reports whether revisions a and b are the same: equal, the same
+// commit hash of different length or equal semantic versions.
+func sameRev(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if godl.IsCommit(a) && godl.IsCommit(b) {
+		return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+	}
+	av, aok := versioned.ParseSemver(a)
+	bv, bok := versioned.ParseSemver(b)
+	return aok && bok && av.Compare(bv) == 0
+}
+
+// revConflict is a dependency from vendor.conf, which vendored dependencies
+// pin at other revisions.
+type revConflict struct {
+	importPath string
+	selected   string
+	wants      []nestedPin
+}
+
+// majorDiverged returns first pin which wants other major version than
+// selected.
+func (c revConflict) majorDiverged() (nestedPin, bool) {
+	sv, ok := versioned.ParseSemver(c.selected)
+	if !ok {
+		return nestedPin{}, false
+	}
+	for _, w := range c.wants {
+		if wv, ok := versioned.ParseSemver(w.dep.rev); ok && wv.Major != sv.Major {
+			return w, true
+		}
+	}
+	return nestedPin{}, false
+}
+
+// findConflicts returns dependencies, which are pinned by vendored
+// dependencies at revisions other than deps selects. Locked commits are
+// taken into account, so commit hashes pinning selected tags are not
+// conflicts.
+func findConflicts(deps []depEntry, lock []lockEntry, pinned nestedPins) []revConflict {
+	commits := make(map[string]string)
+	for _, l := range lock {
+		commits[l.importPath] = l.commit
+	}
+	var conflicts []revConflict
+	for _, d := range deps {
+		wants := pinned[d.importPath]
+		for _, w := range wants {
+			if sameRev(w.dep.rev, d.rev) || (commits[d.importPath] != "" && sameRev(w.dep.rev, commits[d.importPath])) {
+				continue
+			}
+			conflicts = append(conflicts, revConflict{importPath: d.importPath, selected: d.rev, wants: wants})
+			break
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].importPath < conflicts[j].importPath })
+	return conflicts
+}
+
+// printConflicts prints table of conflicts with selected and wanted revisions.
+func printConflicts(w io.Writer, conflicts []revConflict) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMPORT PATH\tREVISION\tWANTED BY")
+	for _, c := range conflicts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.importPath, c.selected, configFile)
+		for _, w := range c.wants {
+			fmt.Fprintf(tw, "\t%s\t%s\n", w.dep.rev, w.by)
+		}
+	}
+	return tw.Flush()
+}
+
+// conflictManifests are nestedManifests read for the conflicts report. The
+// report is made on every vendoring, so module paths of go.mod are compared
+// as they are, without network lookups.
+var conflictManifests = []manifest{
+	{configFile, parseDeps},
+	{goModFile, parseRawGoModDeps},
+}
+
+// reportConflicts prints conflicts of revisions selected in deps with
+// revisions pinned by configs of dependencies vendored in vd. Divergence of
+// major versions is a warning. Dependencies with invalid configs are skipped
+// with a warning.
+func reportConflicts(w io.Writer, vd string, deps []depEntry, lock []lockEntry) error {
+	pinned := nestedPins{}
+	for _, d := range deps {
+		if _, err := pinned.read(vd, d.importPath, conflictManifests); err != nil {
+			Warnf("%v", err)
+		}
+	}
+	conflicts := findConflicts(deps, lock, pinned)
+	if len(conflicts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "Revisions pinned by vendored dependencies differ from vendor.conf:")
+	if err := printConflicts(w, conflicts); err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		if p, ok := c.majorDiverged(); ok {
+			Warnf("%s wants %s %s, but %s selects %s", p.by, c.importPath, p.dep.rev, configFile, c.selected)
+		}
+	}
+	return nil
+}
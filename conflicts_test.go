@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFindConflicts(t *testing.T) {
+	deps := []depEntry{
+		{importPath: "github.com/a/a", rev: "v1.2.0"},
+		{importPath: "github.com/b/b", rev: "v1.0.0"},
+		{importPath: "github.com/c/c", rev: "85ca7c5b95cd0f3e1f2d3c4b5a697887a6b5c4d3"},
+	}
+	lock := []lockEntry{
+		{importPath: "github.com/a/a", rev: "v1.2.0", commit: "645ef00459ed84a119197bfb8d8205042c6df63d"},
+	}
+	pinned := nestedPins{
+		"github.com/a/a": {
+			{by: "github.com/b/b", dep: depEntry{importPath: "github.com/a/a", rev: "645ef00459ed"}},
+			{by: "github.com/c/c", dep: depEntry{importPath: "github.com/a/a", rev: "v1.2.0"}},
+		},
+		"github.com/b/b": {
+			{by: "github.com/a/a", dep: depEntry{importPath: "github.com/b/b", rev: "v2.1.0"}},
+		},
+		"github.com/c/c": {
+			{by: "github.com/a/a", dep: depEntry{importPath: "github.com/c/c", rev: "85ca7c5b95cd"}},
+		},
+	}
+	conflicts := findConflicts(deps, lock, pinned)
+	if len(conflicts) != 1 || conflicts[0].importPath != "github.com/b/b" {
+		t.Fatalf("expected conflict for github.com/b/b only, got %v", conflicts)
+	}
+	p, ok := conflicts[0].majorDiverged()
+	if !ok || p.by != "github.com/a/a" {
+		t.Fatalf("expected major divergence by github.com/a/a, got %v", p)
+	}
+}
+
+func TestReportConflicts(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-conflicts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	writeFiles(t, vd, map[string]string{
+		"github.com/a/a/vendor.conf": "github.com/x/x\n",
+		"github.com/b/b/go.mod":      "module github.com/b/b\n\nrequire \"github.com/a/a v1.0.0\n",
+		"github.com/c/c/go.mod":      "module github.com/c/c\n\nrequire golang.org/x/net v0.0.0-20200301022130-244492dfa37a\n",
+	})
+	deps := []depEntry{
+		{importPath: "github.com/a/a", rev: "v1.0.0"},
+		{importPath: "github.com/b/b", rev: "v1.0.0"},
+		{importPath: "github.com/c/c", rev: "v1.0.0"},
+		{importPath: "golang.org/x/net", rev: "d3edc9973b7e"},
+	}
+	warns := len(Warns())
+	var buf bytes.Buffer
+	if err := reportConflicts(&buf, vd, deps, nil); err != nil {
+		t.Fatal(err)
+	}
+	// vanity import paths are compared without resolving
+	if !strings.Contains(buf.String(), "golang.org/x/net") || !strings.Contains(buf.String(), "244492dfa37a") {
+		t.Fatalf("expected conflict for golang.org/x/net, got\n%s", buf.String())
+	}
+	w := Warns()[warns:]
+	if len(w) != 2 || !strings.HasPrefix(w[0], "github.com/a/a: ") || !strings.HasPrefix(w[1], "github.com/b/b: ") {
+		t.Fatalf("expected warnings for invalid configs of github.com/a/a and github.com/b/b, got %q", w)
+	}
+}
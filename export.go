@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LK4D4/vndr/godl"
+	"github.com/LK4D4/vndr/versioned"
+)
+
+const (
+	goModFile      = "go.mod"
+	modulesTxtFile = "modules.txt"
+	goModHeader    = "// This file is generated by vndr from vendor.conf.\n"
+	// minimal go version for generated go.mod, it is raised to the maximal
+	// version required by dependencies
+	exportGoVersion = "1.16"
+)
+
+// goVersionLess reports whether go version a like "1.9" is less than b.
+// Prerelease suffixes like "rc1" are ignored.
+func goVersionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, bn := leadingNumber(as[i]), leadingNumber(bs[i])
+		if an != bn {
+			return an < bn
+		}
+	}
+	return len(as) < len(bs)
+}
+
+func leadingNumber(s string) int {
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		s = s[:end]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+var majorSuffixRe = regexp.MustCompile(`[/.]v([0-9]+)$`)
+
+// pathMajor returns major version from suffix of import path like
+// github.com/coreos/go-systemd/v22 or gopkg.in/yaml.v2, 0 if there is none.
+func pathMajor(importPath string) int {
+	m := majorSuffixRe.FindStringSubmatch(importPath)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// moduleVersion returns Go module version of dependency vendored in dir.
+// Semantic version tags are used as is, other revisions are converted to
+// pseudo-versions of locked commit.
+func moduleVersion(d depEntry, l lockEntry, dir string) (string, error) {
+	if v, ok := versioned.ParseSemver(d.rev); ok {
+		if v.Major >= 2 && pathMajor(d.importPath) != v.Major {
+			if _, err := os.Stat(filepath.Join(dir, goModFile)); err == nil {
+				Warnf("%s %s has go.mod, but its import path lacks /v%d suffix", d.importPath, d.rev, v.Major)
+			}
+			return d.rev + "+incompatible", nil
+		}
+		return d.rev, nil
+	}
+	if l.commit == "" {
+		return "", fmt.Errorf("%s is not found in %s, run vndr first", d.importPath, lockFile)
+	}
+	t, err := godl.CommitTime(l.vcs, l.repo, l.commit)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", d.importPath, err)
+	}
+	major := pathMajor(d.importPath)
+	if major < 2 {
+		major = 0
+	}
+	commit := l.commit
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return fmt.Sprintf("v%d.0.0-%s-%s", major, t.Format("20060102150405"), commit), nil
+}
+
+// modulePath returns module path of the repository URL, i.e.
+// github.com/LK4D4/vndr for https://github.com/LK4D4/vndr.git. Repositories in
+// local directories are returned as paths to the directories with dir set,
+// go.mod replaces modules by them without versions.
+func modulePath(repo string) (path string, dir bool, err error) {
+	if filepath.IsAbs(repo) || strings.HasPrefix(repo, "./") || strings.HasPrefix(repo, "../") {
+		return filepath.ToSlash(repo), true, nil
+	}
+	u, err := url.Parse(repo)
+	if err != nil {
+		return "", false, err
+	}
+	if u.Scheme == "file" {
+		return u.Path, true, nil
+	}
+	return u.Hostname() + strings.TrimSuffix(u.Path, ".git"), false, nil
+}
+
+// goDirective returns go version from go.mod in dir or empty string.
+func goDirective(dir string) string {
+	f, err := os.Open(filepath.Join(dir, goModFile))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields, err := modFields(s.Text())
+		if err == nil && len(fields) == 2 && fields[0] == "go" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// vendoredPackages returns import paths of packages vendored in vd under
+// root, packages of nested roots are excluded.
+func vendoredPackages(vd, root string, roots []string) ([]string, error) {
+	exclude := nestedRoots(vd, root, roots)
+	var pkgs []string
+	err := filepath.Walk(filepath.Join(vd, root), func(path string, i os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if i.IsDir() {
+			if exclude[path] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		rel, err := filepath.Rel(vd, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if len(pkgs) == 0 || pkgs[len(pkgs)-1] != rel {
+			pkgs = append(pkgs, rel)
+		}
+		return nil
+	})
+	sort.Strings(pkgs)
+	return pkgs, err
+}
+
+// projectModule returns module path of the project in wd from its location
+// in GOPATH.
+func projectModule(wd string) (string, error) {
+	for _, gp := range filepath.SplitList(ctx.GOPATH) {
+		src := filepath.Join(gp, "src")
+		if rel, err := filepath.Rel(src, wd); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not inside of GOPATH %s", wd, ctx.GOPATH)
+}
+
+// exportGoMod writes go.mod and vendor/modules.txt, so go tool accepts vendor
+// directory with -mod=vendor.
+func exportGoMod(wd, vd string) error {
+	if b, err := ioutil.ReadFile(goModFile); err == nil && !bytes.HasPrefix(b, []byte(goModHeader)) {
+		return fmt.Errorf("%s already exists and it is not generated by vndr", goModFile)
+	}
+	module, err := projectModule(wd)
+	if err != nil {
+		return err
+	}
+	deps, err := readDeps()
+	if err != nil {
+		return err
+	}
+	lock, err := readLock(lockFile)
+	if err != nil {
+		return err
+	}
+	locked := make(map[string]lockEntry)
+	for _, l := range lock {
+		locked[l.importPath] = l
+	}
+	var roots []string
+	for _, d := range deps {
+		roots = append(roots, d.importPath)
+	}
+	var requires, replaces []string
+	var modules bytes.Buffer
+	goVersion := exportGoVersion
+	for _, d := range deps {
+		dir := filepath.Join(vd, d.importPath)
+		version, err := moduleVersion(d, locked[d.importPath], dir)
+		if err != nil {
+			return err
+		}
+		requires = append(requires, fmt.Sprintf("\t%s %s\n", d.importPath, version))
+		if d.repoPath != "" {
+			fork, dir, err := modulePath(d.repoPath)
+			if err != nil {
+				return err
+			}
+			if fork != d.importPath {
+				// replaces are versioned, go tool requires the same
+				// replacement in go.mod and modules.txt
+				if !dir {
+					fork += " " + version
+				}
+				replaces = append(replaces, fmt.Sprintf("replace %s %s => %s\n", d.importPath, version, fork))
+				fmt.Fprintf(&modules, "# %s %s => %s\n", d.importPath, version, fork)
+			} else {
+				fmt.Fprintf(&modules, "# %s %s\n", d.importPath, version)
+			}
+		} else {
+			fmt.Fprintf(&modules, "# %s %s\n", d.importPath, version)
+		}
+		if gv := goDirective(dir); gv != "" {
+			fmt.Fprintf(&modules, "## explicit; go %s\n", gv)
+			if goVersionLess(goVersion, gv) {
+				goVersion = gv
+			}
+		} else {
+			modules.WriteString("## explicit\n")
+		}
+		pkgs, err := vendoredPackages(vd, d.importPath, roots)
+		if err != nil {
+			return err
+		}
+		for _, p := range pkgs {
+			modules.WriteString(p + "\n")
+		}
+	}
+	var gomod bytes.Buffer
+	gomod.WriteString(goModHeader)
+	fmt.Fprintf(&gomod, "module %s\n\ngo %s\n", module, goVersion)
+	if len(requires) > 0 {
+		gomod.WriteString("\nrequire (\n" + strings.Join(requires, "") + ")\n")
+	}
+	if len(replaces) > 0 {
+		gomod.WriteString("\n" + strings.Join(replaces, ""))
+	}
+	if err := ioutil.WriteFile(goModFile, gomod.Bytes(), 0666); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(vd, 0777); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(vd, modulesTxtFile), modules.Bytes(), 0666); err != nil {
+		return err
+	}
+	log.Printf("%s and %s are written", goModFile, filepath.Join(vendorDir, modulesTxtFile))
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportGoMod(t *testing.T) {
+	gp, err := ioutil.TempDir("", "vndr-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gp)
+	wd := filepath.Join(gp, "src", "example.com", "p")
+	local := filepath.ToSlash(filepath.Join(gp, "local"))
+	writeFiles(t, wd, map[string]string{
+		"main.go":                        "package main\n\nimport (\n\t_ \"example.com/dep\"\n\t_ \"example.com/fork\"\n\t_ \"example.com/local\"\n)\n\nfunc main() {}\n",
+		configFile:                       "example.com/dep v1.0.0\nexample.com/fork v1.1.0 https://github.com/someone/fork.git\nexample.com/local v1.2.0 " + local + "\n",
+		"vendor/example.com/dep/dep.go":  "package dep\n",
+		"vendor/example.com/fork/go.mod": "module example.com/fork\n\ngo 1.13\n",
+		"vendor/example.com/fork/a.go":   "package fork\n",
+		"vendor/example.com/local/a.go":  "package local\n",
+	})
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(wd); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	defer func(gopath string) { ctx.GOPATH = gopath }(ctx.GOPATH)
+	ctx.GOPATH = gp
+	if err := exportGoMod(wd, filepath.Join(wd, vendorDir)); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		goModFile: goModHeader + `module example.com/p
+
+go 1.16
+
+require (
+	example.com/dep v1.0.0
+	example.com/fork v1.1.0
+	example.com/local v1.2.0
+)
+
+replace example.com/fork v1.1.0 => github.com/someone/fork v1.1.0
+replace example.com/local v1.2.0 => ` + local + `
+`,
+		filepath.Join(vendorDir, modulesTxtFile): `# example.com/dep v1.0.0
+## explicit
+example.com/dep
+# example.com/fork v1.1.0 => github.com/someone/fork v1.1.0
+## explicit; go 1.13
+example.com/fork
+# example.com/local v1.2.0 => ` + local + `
+## explicit
+example.com/local
+`,
+	}
+	for f, content := range expected {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != content {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", f, content, b)
+		}
+	}
+
+	// go tool must accept the exported files without network
+	gobin := filepath.Join(ctx.GOROOT, "bin", "go")
+	if _, err := os.Stat(gobin); err != nil {
+		t.Skip("go tool is not found")
+	}
+	cmd := exec.Command(gobin, "build", "-mod=vendor", "./...")
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=", "GOPROXY=off", "GOWORK=off", "GOTOOLCHAIN=local")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build -mod=vendor: %v, out: %s", err, out)
+	}
+}
@@ -0,0 +1,218 @@
+package godl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheDir is a directory where mirrors of downloaded repositories are kept
+// between runs, so only missing objects are fetched. Cache is not used if
+// CacheDir is empty.
+var CacheDir string
+
+// Offline forbids network access for downloads, repositories are copied from
+// CacheDir only.
+var Offline bool
+
+var (
+	cacheLocksMu sync.Mutex
+	cacheLocks   = map[string]*sync.Mutex{}
+)
+
+// lockCache serializes access to cached mirror, because the same repository
+// can be downloaded concurrently for different import paths.
+func lockCache(cache string) func() {
+	cacheLocksMu.Lock()
+	mu, ok := cacheLocks[cache]
+	if !ok {
+		mu = &sync.Mutex{}
+		cacheLocks[cache] = mu
+	}
+	cacheLocksMu.Unlock()
+	mu.Lock()
+	return mu.Unlock
+}
+
+var unsafeCacheChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// cachePath returns path of cached mirror of repo. Sanitized repo keeps the
+// directory readable, but different repositories can map to the same name, so
+// it is suffixed with a hash of the full repo.
+func (v *vcsCmd) cachePath(repo string) string {
+	sum := sha256.Sum256([]byte(repo))
+	name := unsafeCacheChars.ReplaceAllString(repo, "_") + "-" + hex.EncodeToString(sum[:8])
+	return filepath.Join(CacheDir, v.cmd, name)
+}
+
+// commitRe matches revisions which are commit hashes and can't move.
+var commitRe = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsCommit reports whether revision rev looks like a commit hash, which can't
+// move unlike tags and branches. Tags and branches can have such names too, so
+// references should be checked first where they are available.
+func IsCommit(rev string) bool {
+	return commitRe.MatchString(rev)
+}
+
+// fullCommitRe matches full commit hashes, which servers can be asked for.
+var fullCommitRe = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// updateCache creates or updates mirror of repo in cache. Fetch is skipped if
+// mirror already contains commit rev. Mirrors are created empty and only
+// commit rev is fetched into them if possible, unless history is set, in
+// which case whole history of repo is fetched.
+func (v *vcsCmd) updateCache(cache, repo, rev string, history bool) error {
+	if _, err := os.Stat(cache); err != nil {
+		if Offline {
+			return fmt.Errorf("repository %s is not found in cache %s", repo, CacheDir)
+		}
+		if err := os.MkdirAll(filepath.Dir(cache), 0777); err != nil {
+			return err
+		}
+		if err := v.runCmds(v.cacheCreateCmd, "cache", cache, "repo", repo); err != nil {
+			return err
+		}
+	} else {
+		shallow := history && v.isShallow(cache)
+		if Offline {
+			if shallow {
+				return fmt.Errorf("history of repository %s is not found in cache %s", repo, CacheDir)
+			}
+			return nil
+		}
+		if !shallow && commitRe.MatchString(rev) && v.runVerboseOnly(".", v.cacheHasRevCmd, "cache", cache, "rev", rev) == nil {
+			return nil
+		}
+	}
+	// servers are not required to serve unadvertised commits by hash, so
+	// fall back to fetching everything
+	if !history && fullCommitRe.MatchString(rev) && v.cacheFetchRevCmd != "" &&
+		v.runVerboseOnly(".", v.cacheFetchRevCmd, "cache", cache, "rev", rev) == nil {
+		return nil
+	}
+	return v.runCmds(v.cacheUpdateCmd, "cache", cache)
+}
+
+// isShallow reports whether mirror in cache lacks history of its commits.
+func (v *vcsCmd) isShallow(cache string) bool {
+	if v.cacheShallowCmd == "" {
+		return false
+	}
+	out, err := v.runOutput(".", v.cacheShallowCmd, "cache", cache)
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// runCmds runs cmds one by one and stops on the first error.
+func (v *vcsCmd) runCmds(cmds []string, keyval ...string) error {
+	for _, cmd := range cmds {
+		if out, err := v.runOutput(".", cmd, keyval...); err != nil {
+			return fmt.Errorf("Err: %v, out: %s", err, out)
+		}
+	}
+	return nil
+}
+
+// createFromCache creates a copy of repo in dir from its mirror in cache.
+// The parent of dir must exist; dir must not.
+func (v *vcsCmd) createFromCache(dir, repo, rev string) error {
+	if rev == "" && !Offline && v.listRefsCmd != "" {
+		// fetch only the head commit instead of whole repository
+		refs, err := RemoteRefs(v.cmd, repo)
+		if err != nil {
+			return err
+		}
+		rev = refs["HEAD"]
+	}
+	cache := v.cachePath(repo)
+	unlock := lockCache(cache)
+	defer unlock()
+	if err := v.updateCache(cache, repo, rev, false); err != nil {
+		return err
+	}
+	cmds := v.cacheCopyCmd
+	if rev != "" {
+		// revision can be abbreviated or a tag, which the copy doesn't know
+		out, err := v.runOutput(".", v.cacheResolveCmd, "cache", cache, "rev", rev)
+		if err != nil {
+			return fmt.Errorf("revision %s is not found in %s", rev, repo)
+		}
+		rev = strings.TrimSpace(string(out))
+		cmds = v.cacheCopyRevCmd
+	}
+	if !Offline {
+		cmds = append(cmds[:len(cmds):len(cmds)], v.submoduleCmd...)
+	}
+	if err := v.runCmds(cmds, "cache", cache, "dir", dir, "repo", repo, "rev", rev); err != nil {
+		return err
+	}
+	// submodules are not mirrored, vendoring without them would produce
+	// another tree than online
+	if Offline && v.submoduleFile != "" {
+		if _, err := os.Stat(filepath.Join(dir, v.submoduleFile)); err == nil {
+			return fmt.Errorf("repository %s has submodules, which can't be fetched in offline mode", repo)
+		}
+	}
+	return nil
+}
+
+// CommitTime returns time of commit rev in repository repo of version control
+// system vcsType. It requires CacheDir, the mirror of repo is created or
+// updated unless Offline is set.
+func CommitTime(vcsType, repo, rev string) (time.Time, error) {
+	out, err := cacheOutput(vcsType, repo, rev, false, func(v *vcsCmd) string { return v.cacheTimeCmd }, "rev", rev)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse commit time %q", out)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// CommitsBehind returns number of commits of head which are not ancestors of
+// rev in repository repo of version control system vcsType. Like CommitTime
+// it requires CacheDir.
+func CommitsBehind(vcsType, repo, rev, head string) (int, error) {
+	out, err := cacheOutput(vcsType, repo, head, true, func(v *vcsCmd) string { return v.cacheCountCmd }, "rev", rev, "head", head)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse number of commits %q", out)
+	}
+	return n, nil
+}
+
+// cacheOutput runs command returned by cmd in the mirror of repo, which is
+// updated to contain commit rev and whole history if history is set, and
+// returns its output.
+func cacheOutput(vcsType, repo, rev string, history bool, cmd func(v *vcsCmd) string, keyval ...string) ([]byte, error) {
+	v := vcsByCmd(vcsType)
+	if v == nil {
+		return nil, fmt.Errorf("unknown version control system %q", vcsType)
+	}
+	if CacheDir == "" || cmd(v) == "" {
+		return nil, fmt.Errorf("history of %s repositories requires cache", v.name)
+	}
+	cache := v.cachePath(repo)
+	unlock := lockCache(cache)
+	defer unlock()
+	if err := v.updateCache(cache, repo, rev, history); err != nil {
+		return nil, err
+	}
+	out, err := v.runOutput(".", cmd(v), append([]string{"cache", cache}, keyval...)...)
+	if err != nil {
+		return nil, fmt.Errorf("Err: %v, out: %s", err, out)
+	}
+	return out, nil
+}
@@ -17,6 +17,7 @@ type VCS struct {
 	Root       string
 	ImportPath string
 	Type       string
+	Repo       string
 }
 
 // Download downloads package by its import path. It can be a subpackage,
@@ -27,6 +28,37 @@ type VCS struct {
 // vendor/github.com/LK4D4/vndr.
 // rev is desired revision of package.
 func Download(importPath, repoPath, target, rev string) (*VCS, error) {
+	rr, err := resolveRepoRoot(importPath, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return download(rr, target, rev)
+}
+
+// Resolve determines version control system and repository of package
+// without downloading it. Root of returned VCS is empty.
+func Resolve(importPath, repoPath string) (*VCS, error) {
+	rr, err := resolveRepoRoot(importPath, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return &VCS{ImportPath: rr.root, Type: rr.vcs.cmd, Repo: rr.repo}, nil
+}
+
+// DownloadRepo is like Download, but it doesn't analyze import path. vcsType
+// is the name of vcs command, i.e. "git" and repo is the repository URL
+// including scheme.
+func DownloadRepo(importPath, vcsType, repo, target, rev string) (*VCS, error) {
+	vcs := vcsByCmd(vcsType)
+	if vcs == nil {
+		return nil, fmt.Errorf("unknown version control system %q", vcsType)
+	}
+	return download(&repoRoot{vcs: vcs, repo: repo, root: importPath}, target, rev)
+}
+
+// resolveRepoRoot determines vcs and repository for importPath. If repoPath is
+// not empty, it is used as repository.
+func resolveRepoRoot(importPath, repoPath string) (*repoRoot, error) {
 	var (
 		security = secure
 		rr       *repoRoot
@@ -66,24 +98,31 @@ func Download(importPath, repoPath, target, rev string) (*VCS, error) {
 			return nil, err
 		}
 	}
+	return rr, nil
+}
 
+func download(rr *repoRoot, target, rev string) (*VCS, error) {
 	root := filepath.Join(target, rr.root)
 	if err := os.RemoveAll(root); err != nil {
 		return nil, fmt.Errorf("remove package root: %v", err)
 	}
 	// Some version control tools require the parent of the target to exist.
 	parent, _ := filepath.Split(root)
-	if err = os.MkdirAll(parent, 0777); err != nil {
+	if err := os.MkdirAll(parent, 0777); err != nil {
 		return nil, err
 	}
-	if rev == "" {
-		if err = rr.vcs.create(root, rr.repo); err != nil {
-			return nil, err
-		}
+	var err error
+	if CacheDir != "" && rr.vcs.cacheCreateCmd != nil {
+		err = rr.vcs.createFromCache(root, rr.repo, rev)
+	} else if Offline {
+		err = fmt.Errorf("%s repositories can't be downloaded in offline mode", rr.vcs.name)
+	} else if rev == "" {
+		err = rr.vcs.create(root, rr.repo)
 	} else {
-		if err = rr.vcs.createRev(root, rr.repo, rev); err != nil {
-			return nil, err
-		}
+		err = rr.vcs.createRev(root, rr.repo, rev)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return &VCS{Root: root, ImportPath: rr.root, Type: rr.vcs.cmd}, nil
+	return &VCS{Root: root, ImportPath: rr.root, Type: rr.vcs.cmd, Repo: rr.repo}, nil
 }
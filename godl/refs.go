@@ -0,0 +1,60 @@
+package godl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RemoteRefs returns references of repository repo of version control system
+// vcsType mapped to their commits, i.e. "HEAD", "refs/heads/master" or
+// "refs/tags/v1.0.0". Annotated tags are mapped to commits they point to.
+// In Offline mode references of cached mirror are returned, which requires
+// its whole history.
+func RemoteRefs(vcsType, repo string) (map[string]string, error) {
+	v := vcsByCmd(vcsType)
+	if v == nil {
+		return nil, fmt.Errorf("unknown version control system %q", vcsType)
+	}
+	if v.listRefsCmd == "" {
+		return nil, fmt.Errorf("listing references of %s repositories is not supported", v.name)
+	}
+	remote := repo
+	if Offline {
+		if CacheDir == "" || v.cacheCreateCmd == nil {
+			return nil, fmt.Errorf("%s repositories can't be queried in offline mode", v.name)
+		}
+		remote = v.cachePath(repo)
+		if _, err := os.Stat(remote); err != nil {
+			return nil, fmt.Errorf("repository %s is not found in cache %s", repo, CacheDir)
+		}
+		// mirrors filled by shallow fetches lack references
+		if v.isShallow(remote) {
+			return nil, fmt.Errorf("references of repository %s are not found in cache %s", repo, CacheDir)
+		}
+	}
+	out, err := v.runOutput(".", v.listRefsCmd, "repo", remote)
+	if err != nil {
+		return nil, fmt.Errorf("Err: %v, out: %s", err, out)
+	}
+	refs := make(map[string]string)
+	peeled := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 || !commitRe.MatchString(fields[0]) {
+			continue
+		}
+		if ref := strings.TrimSuffix(fields[1], "^{}"); ref != fields[1] {
+			peeled[ref] = fields[0]
+			continue
+		}
+		refs[fields[1]] = fields[0]
+	}
+	for ref, commit := range peeled {
+		refs[ref] = commit
+	}
+	return refs, nil
+}
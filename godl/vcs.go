@@ -57,6 +57,25 @@ type vcsCmd struct {
 	createCmd    []string // commands to download a fresh copy of a repository
 	createRevCmd []string // commands to download specified revision of a repository
 
+	// commands to download specified revision of a repository if
+	// createRevCmd failed, i.e. because server refused to serve it
+	createRevFallbackCmd []string
+
+	cacheCreateCmd   []string // commands to create an empty mirror of a repository in cache
+	cacheUpdateCmd   []string // commands to fetch whole history into a cached mirror
+	cacheFetchRevCmd string   // command to fetch only specified commit into a cached mirror
+	cacheHasRevCmd   string   // command to check that a cached mirror contains revision
+	cacheShallowCmd  string   // command to print "true" if a cached mirror lacks history
+	cacheResolveCmd  string   // command to print commit of revision in a cached mirror
+	cacheTimeCmd     string   // command to print commit time of revision in a cached mirror as unix time
+	cacheCountCmd    string   // command to print number of commits from revision to head in a cached mirror
+	cacheCopyCmd     []string // commands to create a copy of head of a repository from a cached mirror
+	cacheCopyRevCmd  []string // commands to create a copy of specified commit of a repository from a cached mirror
+	submoduleCmd     []string // commands to fetch submodules of a copy
+	submoduleFile    string   // file which lists submodules of a copy
+
+	listRefsCmd string // command to list references of a repository with their commits
+
 	scheme  []string
 	pingCmd string
 
@@ -118,7 +137,30 @@ var vcsGit = &vcsCmd{
 	cmd:  "git",
 
 	createCmd:    []string{"clone {repo} {dir}", "-C {dir} submodule update --init --recursive"},
-	createRevCmd: []string{"clone {repo} {dir}", "-C {dir} submodule update --init --recursive", "-C {dir} checkout {rev}", "-C {dir} reset --hard {rev}"},
+	createRevCmd: []string{"init -q {dir}", "-C {dir} remote add origin {repo}", "-C {dir} fetch --depth 1 origin {rev}", "-C {dir} checkout FETCH_HEAD", "-C {dir} submodule update --init --recursive"},
+
+	// Servers are not required to serve unadvertised commits by sha, so
+	// fall back to the full clone.
+	createRevFallbackCmd: []string{"clone {repo} {dir}", "-C {dir} submodule update --init --recursive", "-C {dir} checkout {rev}", "-C {dir} reset --hard {rev}"},
+
+	// Mirrors are filled on demand by shallow fetches of vendored commits,
+	// so the whole history is fetched only for commands which need it. The
+	// depth of cacheUpdateCmd completes shallow mirrors like --unshallow,
+	// which fails on complete ones.
+	cacheCreateCmd:   []string{"init -q --bare {cache}", "-C {cache} remote add --mirror=fetch origin {repo}"},
+	cacheUpdateCmd:   []string{"-C {cache} fetch --prune --depth=2147483647 origin"},
+	cacheFetchRevCmd: "-C {cache} fetch --depth 1 origin {rev}",
+	cacheHasRevCmd:   "-C {cache} cat-file -e {rev}^{commit}",
+	cacheShallowCmd:  "-C {cache} rev-parse --is-shallow-repository",
+	cacheResolveCmd:  "-C {cache} rev-parse --verify -q {rev}^{commit}",
+	cacheTimeCmd:     "-C {cache} log -1 --format=%ct {rev}",
+	cacheCountCmd:    "-C {cache} rev-list --count {rev}..{head}",
+	cacheCopyCmd:     []string{"init -q {dir}", "-C {dir} remote add origin {repo}", "-C {dir} fetch -q --depth 1 {cache}", "-C {dir} checkout -q FETCH_HEAD"},
+	cacheCopyRevCmd:  []string{"init -q {dir}", "-C {dir} remote add origin {repo}", "-C {dir} fetch -q --depth 1 {cache} {rev}", "-C {dir} checkout -q FETCH_HEAD"},
+	submoduleCmd:     []string{"-C {dir} submodule update --init --recursive"},
+	submoduleFile:    ".gitmodules",
+
+	listRefsCmd: "ls-remote {repo}",
 
 	scheme:     []string{"https", "http", "git+ssh", "ssh", "git"},
 	pingCmd:    "ls-remote {scheme}://{repo}",
@@ -332,8 +374,21 @@ func (v *vcsCmd) create(dir, repo string) error {
 	return nil
 }
 
+// createRev creates a copy of revision rev of repo in dir.
+// The parent of dir must exist; dir must not.
 func (v *vcsCmd) createRev(dir, repo, rev string) error {
-	for _, cmd := range v.createRevCmd {
+	err := v.runCreateRev(v.createRevCmd, dir, repo, rev)
+	if err == nil || v.createRevFallbackCmd == nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return v.runCreateRev(v.createRevFallbackCmd, dir, repo, rev)
+}
+
+func (v *vcsCmd) runCreateRev(cmds []string, dir, repo, rev string) error {
+	for _, cmd := range cmds {
 		if out, err := v.runOutput(".", cmd, "dir", dir, "repo", repo, "rev", rev); err != nil {
 			return fmt.Errorf("Err: %v, out: %s", err, out)
 		}
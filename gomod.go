@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/LK4D4/vndr/godl"
+	"github.com/LK4D4/vndr/versioned"
+)
+
+// modVersion is a module path with optional version.
+type modVersion struct {
+	path    string
+	version string
+}
+
+type modReplace struct {
+	old modVersion
+	new modVersion
+}
+
+// goMod is a subset of go.mod file, which is interesting for vendoring.
+type goMod struct {
+	module  string
+	require []modVersion
+	replace []modReplace
+	exclude []modVersion
+}
+
+// modFields splits go.mod line into fields, removing comments and quotes.
+func modFields(ln string) ([]string, error) {
+	var fields []string
+	for ln = strings.TrimSpace(ln); ln != ""; ln = strings.TrimSpace(ln) {
+		if strings.HasPrefix(ln, "//") {
+			break
+		}
+		if ln[0] == '"' || ln[0] == '`' {
+			q, err := quotedPrefix(ln)
+			if err != nil {
+				return nil, err
+			}
+			f, err := strconv.Unquote(q)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, f)
+			ln = ln[len(q):]
+			continue
+		}
+		i := strings.IndexAny(ln, " \t")
+		if i < 0 {
+			i = len(ln)
+		}
+		fields = append(fields, ln[:i])
+		ln = ln[i:]
+	}
+	return fields, nil
+}
+
+// quotedPrefix returns quoted string at the beginning of ln, including
+// quotes. Backslash escapes the next character in interpreted strings.
+func quotedPrefix(ln string) (string, error) {
+	quote := ln[0]
+	for i := 1; i < len(ln); i++ {
+		switch {
+		case ln[i] == '\\' && quote == '"':
+			i++
+		case ln[i] == quote:
+			return ln[:i+1], nil
+		}
+	}
+	return "", strconv.ErrSyntax
+}
+
+func parseGoMod(r io.Reader) (*goMod, error) {
+	m := &goMod{}
+	var block string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f, err := modFields(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid go.mod line %q: %v", s.Text(), err)
+		}
+		if len(f) == 0 {
+			continue
+		}
+		verb := block
+		if block == "" {
+			verb, f = f[0], f[1:]
+			if len(f) == 1 && f[0] == "(" {
+				block = verb
+				continue
+			}
+		} else if len(f) == 1 && f[0] == ")" {
+			block = ""
+			continue
+		}
+		if err := m.add(verb, f); err != nil {
+			return nil, fmt.Errorf("invalid go.mod line %q: %v", s.Text(), err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *goMod) add(verb string, f []string) error {
+	switch verb {
+	case "module":
+		if len(f) != 1 {
+			return fmt.Errorf("usage: module path")
+		}
+		m.module = f[0]
+	case "require", "exclude":
+		if len(f) != 2 {
+			return fmt.Errorf("usage: %s module/path v1.2.3", verb)
+		}
+		mv := modVersion{path: f[0], version: f[1]}
+		if verb == "require" {
+			m.require = append(m.require, mv)
+		} else {
+			m.exclude = append(m.exclude, mv)
+		}
+	case "replace":
+		arrow := -1
+		for i, s := range f {
+			if s == "=>" {
+				arrow = i
+			}
+		}
+		if arrow < 1 || arrow > 2 || len(f)-arrow-1 < 1 || len(f)-arrow-1 > 2 {
+			return fmt.Errorf("usage: replace module/path [v1.2.3] => other/module [v1.4.5]")
+		}
+		var r modReplace
+		r.old.path = f[0]
+		if arrow == 2 {
+			r.old.version = f[1]
+		}
+		r.new.path = f[arrow+1]
+		if len(f) == arrow+3 {
+			r.new.version = f[arrow+2]
+		}
+		m.replace = append(m.replace, r)
+	}
+	return nil
+}
+
+func isLocalModPath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || strings.HasPrefix(p, "/") || p == "." || p == ".."
+}
+
+// goModDeps translates requirements of go.mod to vendor.conf entries.
+// Module versions become tags or commit hashes for pseudo-versions,
+// replacements become repositories. Repositories of modules are determined by
+// resolve. Unsupported directives are reported with warnf.
+func goModDeps(m *goMod, resolve func(path string) (*godl.VCS, error), warnf func(format string, a ...interface{})) []depEntry {
+	excluded := make(map[modVersion]bool)
+	for _, e := range m.exclude {
+		excluded[e] = true
+	}
+	var deps []depEntry
+	seen := make(map[string]string)
+	for _, req := range m.require {
+		if excluded[req] {
+			warnf("required module %s %s is excluded in go.mod", req.path, req.version)
+		}
+		d, _, err := modDep(req, resolve)
+		if err != nil {
+			warnf("%v", err)
+		}
+		for _, r := range m.replace {
+			if r.old.path != req.path || (r.old.version != "" && r.old.version != req.version) {
+				continue
+			}
+			if isLocalModPath(r.new.path) {
+				warnf("module %s is replaced by local directory %s, revision %s is used", req.path, r.new.path, req.version)
+				break
+			}
+			rd, v, err := modDep(r.new, resolve)
+			if err != nil {
+				warnf("module %s is replaced by %s: %v, revision %s is used", req.path, r.new.path, err, req.version)
+				break
+			}
+			if rd.importPath != r.new.path {
+				warnf("module %s is replaced by module %s in subdirectory of repository %s, revision %s is used", req.path, r.new.path, rd.importPath, req.version)
+				break
+			}
+			d.rev = rd.rev
+			if r.new.path != req.path {
+				d.repoPath, d.vcs = v.Repo, v.Type
+			}
+			break
+		}
+		if by, ok := seen[d.importPath]; ok {
+			warnf("module %s is in repository %s of module %s, which is already required", req.path, d.importPath, by)
+			continue
+		}
+		seen[d.importPath] = req.path
+		deps = append(deps, d)
+	}
+	return deps
+}
+
+// modDep returns vendor.conf entry for module version mv and repository of
+// the module. Modules in subdirectories of repositories are tagged with the
+// subdirectory as prefix, like autorest/v0.11.0, the whole repository is
+// vendored for them. On error entry of mv as it is is returned.
+func modDep(mv modVersion, resolve func(path string) (*godl.VCS, error)) (depEntry, *godl.VCS, error) {
+	d := depEntry{importPath: mv.path, rev: versioned.Revision(mv.version)}
+	v, err := resolve(mv.path)
+	if err != nil {
+		return d, nil, fmt.Errorf("unable to resolve module %s: %v", mv.path, err)
+	}
+	// major version suffix is not a directory
+	dir := strings.TrimPrefix(majorSuffixRe.ReplaceAllString(strings.TrimPrefix(mv.path, v.ImportPath), ""), "/")
+	if dir == "" {
+		return d, v, nil
+	}
+	d.importPath = v.ImportPath
+	if !versioned.IsPseudo(mv.version) {
+		d.rev = dir + "/" + d.rev
+	}
+	return d, v, nil
+}
+
+var (
+	resolvedModulesMu sync.Mutex
+	resolvedModules   = map[string]*godl.VCS{}
+)
+
+// resolveModule determines repository of module path like repository of
+// import path is determined for vendoring.
+func resolveModule(path string) (*godl.VCS, error) {
+	resolvedModulesMu.Lock()
+	defer resolvedModulesMu.Unlock()
+	if v, ok := resolvedModules[path]; ok {
+		return v, nil
+	}
+	v, err := godl.Resolve(path, "")
+	if err != nil {
+		return nil, err
+	}
+	resolvedModules[path] = v
+	return v, nil
+}
+
+// importGoMod writes vendor.conf with requirements from go.mod file.
+func importGoMod(goModFile string) error {
+	if _, err := os.Stat(configFile); err == nil {
+		return fmt.Errorf("%s already exists", configFile)
+	}
+	f, err := os.Open(goModFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	m, err := parseGoMod(f)
+	if err != nil {
+		return err
+	}
+	if err := writeConfig(goModDeps(m, resolveModule, Warnf), configFile); err != nil {
+		return err
+	}
+	log.Printf("Requirements of %s are written to %s", goModFile, configFile)
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LK4D4/vndr/godl"
+)
+
+const testGoMod = `module github.com/example/project // comment
+
+go 1.16
+
+require (
+	github.com/coreos/go-systemd/v22 v22.0.0
+	github.com/docker/docker v17.12.0-ce-rc1.0.20200309214505-aa6a9891b09c+incompatible // indirect
+	"golang.org/x/sys" v0.0.0-20200323222414-85ca7c5b95cd
+	github.com/pkg/errors v0.9.1
+	github.com/local/pkg v1.0.0
+	github.com/Azure/go-autorest/autorest v0.11.0
+	github.com/Azure/go-autorest/logger v0.2.0
+	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e
+)
+
+require gopkg.in/yaml.v2 v2.2.8
+
+replace github.com/pkg/errors => github.com/LK4D4/errors v0.8.0
+
+replace golang.org/x/net => golang.org/x/net v0.0.0-20200301022130-244492dfa37a
+
+replace golang.org/x/sys => golang.org/x/fork v1.0.0
+
+replace (
+	github.com/local/pkg => ../pkg
+	gopkg.in/yaml.v2 v2.2.7 => gopkg.in/yaml.v2 v2.2.2
+)
+
+exclude github.com/pkg/errors v0.9.0
+`
+
+func TestGoModDeps(t *testing.T) {
+	m, err := parseGoMod(strings.NewReader(testGoMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.module != "github.com/example/project" {
+		t.Fatalf("unexpected module %q", m.module)
+	}
+	expected := []depEntry{
+		{importPath: "github.com/coreos/go-systemd/v22", rev: "v22.0.0"},
+		{importPath: "github.com/docker/docker", rev: "aa6a9891b09c"},
+		{importPath: "golang.org/x/sys", rev: "85ca7c5b95cd"},
+		{importPath: "github.com/pkg/errors", rev: "v0.8.0", repoPath: "https://github.com/LK4D4/errors", vcs: "git"},
+		{importPath: "github.com/local/pkg", rev: "v1.0.0"},
+		{importPath: "github.com/Azure/go-autorest", rev: "autorest/v0.11.0"},
+		{importPath: "golang.org/x/net", rev: "244492dfa37a"},
+		{importPath: "gopkg.in/yaml.v2", rev: "v2.2.8"},
+	}
+	// vanity import paths are resolved by network, fork of golang.org/x/sys
+	// isn't found
+	repos := map[string]string{
+		"golang.org/x/sys": "https://go.googlesource.com/sys",
+		"golang.org/x/net": "https://go.googlesource.com/net",
+		"gopkg.in/yaml.v2": "https://gopkg.in/yaml.v2",
+	}
+	resolve := func(path string) (*godl.VCS, error) {
+		if repo, ok := repos[path]; ok {
+			return &godl.VCS{ImportPath: path, Type: "git", Repo: repo}, nil
+		}
+		parts := strings.Split(path, "/")
+		if parts[0] != "github.com" {
+			return nil, fmt.Errorf("unrecognized import path %q", path)
+		}
+		root := strings.Join(parts[:3], "/")
+		return &godl.VCS{ImportPath: root, Type: "git", Repo: "https://" + root}, nil
+	}
+	var warns []string
+	deps := goModDeps(m, resolve, func(format string, a ...interface{}) {
+		warns = append(warns, fmt.Sprintf(format, a...))
+	})
+	if !reflect.DeepEqual(deps, expected) {
+		t.Fatalf("expected\n%v\ngot\n%v", expected, deps)
+	}
+	expectedWarns := []string{
+		`module golang.org/x/sys is replaced by golang.org/x/fork: unable to resolve module golang.org/x/fork: unrecognized import path "golang.org/x/fork", revision v0.0.0-20200323222414-85ca7c5b95cd is used`,
+		"module github.com/local/pkg is replaced by local directory ../pkg, revision v1.0.0 is used",
+		"module github.com/Azure/go-autorest/logger is in repository github.com/Azure/go-autorest of module github.com/Azure/go-autorest/autorest, which is already required",
+	}
+	if !reflect.DeepEqual(warns, expectedWarns) {
+		t.Fatalf("expected warnings\n%q\ngot\n%q", expectedWarns, warns)
+	}
+}
+
+func TestModFields(t *testing.T) {
+	cases := []struct {
+		line   string
+		fields []string
+	}{
+		{`require "golang.org/x/sys" v1.0.0 // indirect`, []string{"require", "golang.org/x/sys", "v1.0.0"}},
+		{`replace "a\"b" => ` + "`c\\d`" + ` v1.0.0`, []string{"replace", `a"b`, "=>", `c\d`, "v1.0.0"}},
+		{`"a\\" b`, []string{`a\`, "b"}},
+	}
+	for _, tc := range cases {
+		f, err := modFields(tc.line)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.line, err)
+		}
+		if !reflect.DeepEqual(f, tc.fields) {
+			t.Fatalf("%s: expected %q, got %q", tc.line, tc.fields, f)
+		}
+	}
+	for _, ln := range []string{`require "golang.org/x/sys v1.0.0`, `"a\" b`, "`a b"} {
+		if _, err := modFields(ln); err == nil {
+			t.Fatalf("%s: expected error for unterminated quote", ln)
+		}
+	}
+}
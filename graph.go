@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/LK4D4/vndr/build"
+)
+
+// kinds of graph edges, from the strongest one
+const (
+	edgeImport = "import"
+	edgeTest   = "test"
+	edgeXTest  = "xtest"
+)
+
+var edgeRank = map[string]int{edgeImport: 0, edgeTest: 1, edgeXTest: 2}
+
+// depGraph is import graph of packages or repositories.
+type depGraph struct {
+	nodes map[string]bool
+	// edges maps importing node to imported nodes and kinds of edges
+	edges map[string]map[string]string
+	// roots are nodes of the project
+	roots map[string]bool
+}
+
+func newDepGraph() *depGraph {
+	return &depGraph{nodes: make(map[string]bool), edges: make(map[string]map[string]string), roots: make(map[string]bool)}
+}
+
+// addEdge adds edge of kind from node from to node to. Of several edges
+// between the same nodes the strongest kind is kept.
+func (g *depGraph) addEdge(from, to, kind string) {
+	g.nodes[from] = true
+	g.nodes[to] = true
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]string)
+	}
+	if k, ok := g.edges[from][to]; ok && edgeRank[k] <= edgeRank[kind] {
+		return
+	}
+	g.edges[from][to] = kind
+}
+
+// unvendor returns import path of package without vendor dir prefix.
+func unvendor(imp string) string {
+	if i := strings.LastIndex(imp, "/"+vendorDir+"/"); i >= 0 {
+		return imp[i+len(vendorDir)+2:]
+	}
+	return strings.TrimPrefix(imp, vendorDir+"/")
+}
+
+// packageGraph builds import graph of pkgs collected by collectAllDeps. Test
+// imports of initPkgs are test and xtest edges, imports of packages outside of
+// pkgs are skipped. initPkgs are roots of the graph.
+func packageGraph(pkgs, initPkgs []*build.Package) *depGraph {
+	g := newDepGraph()
+	known := make(map[string]bool)
+	for _, pkg := range pkgs {
+		known[unvendor(strings.TrimRight(pkg.ImportPath, "/"))] = true
+	}
+	isInit := make(map[*build.Package]bool)
+	for _, pkg := range initPkgs {
+		isInit[pkg] = true
+	}
+	for _, pkg := range pkgs {
+		from := unvendor(strings.TrimRight(pkg.ImportPath, "/"))
+		g.nodes[from] = true
+		if isInit[pkg] {
+			g.roots[from] = true
+		}
+		add := func(imps []string, kind string) {
+			for _, imp := range imps {
+				if known[imp] && imp != from {
+					g.addEdge(from, imp, kind)
+				}
+			}
+		}
+		add(pkg.Imports, edgeImport)
+		if isInit[pkg] {
+			add(pkg.TestImports, edgeTest)
+			add(pkg.XTestImports, edgeXTest)
+		}
+	}
+	return g
+}
+
+// repoGraph collapses package graph g to graph of repositories. Packages of
+// the project with import path project and of deps are merged into their
+// roots, other packages stay as they are.
+func repoGraph(g *depGraph, project string, deps []depEntry) *depGraph {
+	repo := func(imp string) string {
+		if imp == project || strings.HasPrefix(imp, project+"/") {
+			return project
+		}
+		// depOwner matches paths inside of dependencies, the slash makes
+		// root packages such paths too
+		if d, _, ok := depOwner(deps, imp+"/"); ok {
+			return d.importPath
+		}
+		return imp
+	}
+	rg := newDepGraph()
+	for n := range g.nodes {
+		rg.nodes[repo(n)] = true
+	}
+	rg.roots[project] = true
+	for from, tos := range g.edges {
+		for to, kind := range tos {
+			if rf, rt := repo(from), repo(to); rf != rt {
+				rg.addEdge(rf, rt, kind)
+			}
+		}
+	}
+	return rg
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+type graphJSON struct {
+	Nodes []string    `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+// sorted returns nodes and edges of g in stable order.
+func (g *depGraph) sorted() ([]string, []graphEdge) {
+	nodes := []string{}
+	for n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	edges := []graphEdge{}
+	for _, from := range nodes {
+		var tos []string
+		for to := range g.edges[from] {
+			tos = append(tos, to)
+		}
+		sort.Strings(tos)
+		for _, to := range tos {
+			edges = append(edges, graphEdge{From: from, To: to, Kind: g.edges[from][to]})
+		}
+	}
+	return nodes, edges
+}
+
+// printGraph writes g to w in Graphviz DOT format or as JSON. Test and xtest
+// edges are dashed in DOT.
+func printGraph(w io.Writer, g *depGraph, asJSON bool) error {
+	nodes, edges := g.sorted()
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(graphJSON{Nodes: nodes, Edges: edges})
+	}
+	if _, err := fmt.Fprintln(w, "digraph deps {"); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		fmt.Fprintf(w, "\t%q;\n", n)
+	}
+	for _, e := range edges {
+		if e.Kind != edgeImport {
+			fmt.Fprintf(w, "\t%q -> %q [style=dashed];\n", e.From, e.To)
+			continue
+		}
+		fmt.Fprintf(w, "\t%q -> %q;\n", e.From, e.To)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// vendorGraph builds import graph of packages of the project in wd and its
+// vendored dependencies. If repos is set, graph of repositories is built.
+func vendorGraph(wd string, repos bool) (*depGraph, error) {
+	initPkgs, err := collectPkgs(wd)
+	if err != nil {
+		return nil, fmt.Errorf("Error collecting initial packages: %v", err)
+	}
+	pkgs, err := collectAllDeps(wd, nil, nil, initPkgs...)
+	if err != nil {
+		return nil, err
+	}
+	g := packageGraph(pkgs, initPkgs)
+	if !repos {
+		return g, nil
+	}
+	deps, err := readDeps()
+	if err != nil {
+		return nil, err
+	}
+	project, err := projectModule(wd)
+	if err != nil {
+		return nil, err
+	}
+	return repoGraph(g, project, deps), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LK4D4/vndr/build"
+)
+
+func TestPackageGraph(t *testing.T) {
+	p := &build.Package{ImportPath: "example.com/p", Imports: []string{"fmt", "github.com/a/a"}, TestImports: []string{"github.com/b/b/assert"}}
+	a := &build.Package{ImportPath: "example.com/p/vendor/github.com/a/a", Imports: []string{"github.com/a/a/internal", "github.com/b/b"}}
+	ai := &build.Package{ImportPath: "example.com/p/vendor/github.com/a/a/internal"}
+	b := &build.Package{ImportPath: "example.com/p/vendor/github.com/b/b"}
+	ba := &build.Package{ImportPath: "example.com/p/vendor/github.com/b/b/assert", Imports: []string{"github.com/b/b"}}
+	g := packageGraph([]*build.Package{p, a, ai, b, ba}, []*build.Package{p})
+
+	var out bytes.Buffer
+	if err := printGraph(&out, g, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph deps {
+	"example.com/p";
+	"github.com/a/a";
+	"github.com/a/a/internal";
+	"github.com/b/b";
+	"github.com/b/b/assert";
+	"example.com/p" -> "github.com/a/a";
+	"example.com/p" -> "github.com/b/b/assert" [style=dashed];
+	"github.com/a/a" -> "github.com/a/a/internal";
+	"github.com/a/a" -> "github.com/b/b";
+	"github.com/b/b/assert" -> "github.com/b/b";
+}
+`
+	if out.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	rg := repoGraph(g, "example.com/p", []depEntry{{importPath: "github.com/a/a"}, {importPath: "github.com/b/b"}})
+	_, edges := rg.sorted()
+	expectedEdges := []graphEdge{
+		{From: "example.com/p", To: "github.com/a/a", Kind: edgeImport},
+		{From: "example.com/p", To: "github.com/b/b", Kind: edgeTest},
+		{From: "github.com/a/a", To: "github.com/b/b", Kind: edgeImport},
+	}
+	if len(edges) != len(expectedEdges) {
+		t.Fatalf("expected %v, got %v", expectedEdges, edges)
+	}
+	for i, e := range edges {
+		if e != expectedEdges[i] {
+			t.Fatalf("expected %v, got %v", expectedEdges[i], e)
+		}
+	}
+}
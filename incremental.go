@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/LK4D4/vndr/build"
+	"github.com/LK4D4/vndr/godl"
+)
+
+// unchangedDeps returns lock entries of deps which are vendored in vd exactly
+// as lock describes: with the same revision, repository and cleaning settings
+// and unmodified content. Such deps don't need to be cloned again. Tags and
+// branches can move, so they are unchanged only if they still resolve to the
+// vendored commit.
+func unchangedDeps(vd string, deps []depEntry, lock []lockEntry) (map[string]lockEntry, error) {
+	locked := make(map[string]lockEntry)
+	var imps []string
+	for _, l := range lock {
+		locked[l.importPath] = l
+		imps = append(imps, l.importPath)
+	}
+	var candidates []depEntry
+	for _, d := range deps {
+		l, ok := locked[d.importPath]
+		if !ok || l.rev != d.rev || (d.repoPath != "" && d.repoPath != l.repo) || l.clean != cleanInputs(d) {
+			continue
+		}
+		h, err := hashDir(filepath.Join(vd, d.importPath), nestedRoots(vd, d.importPath, imps))
+		if err != nil {
+			return nil, err
+		}
+		if h == l.hash {
+			candidates = append(candidates, d)
+		}
+	}
+	unchanged := make(map[string]lockEntry)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, 16)
+	for _, d := range candidates {
+		l := locked[d.importPath]
+		if godl.IsCommit(d.rev) {
+			if strings.HasPrefix(l.commit, d.rev) {
+				unchanged[d.importPath] = l
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(d depEntry, l lockEntry) {
+			limit <- struct{}{}
+			// on error dependency is cloned and the error is reported then
+			commit, err := resolveRev(d)
+			if err == nil && commit == l.commit {
+				mu.Lock()
+				unchanged[d.importPath] = l
+				mu.Unlock()
+			}
+			<-limit
+			wg.Done()
+		}(d, l)
+	}
+	wg.Wait()
+	return unchanged, nil
+}
+
+// cleanInputs returns hash of settings which cleaning of vendored d depends
+// on: its keep, drop and subpackages attributes, whitelist, platforms and
+// ignored tags. It is recorded to lock, because vendored copy of d cleaned
+// with other settings can't be reused.
+func cleanInputs(d depEntry) string {
+	lines := []string{
+		"keep " + strings.Join(d.keep, ",") + "\n",
+		"drop " + strings.Join(d.drop, ",") + "\n",
+		"subpackages " + strings.Join(d.subpackages, ",") + "\n",
+	}
+	for _, re := range cleanWhitelist {
+		lines = append(lines, "whitelist "+re.String()+"\n")
+	}
+	for _, t := range ctx.Targets {
+		lines = append(lines, "platform "+strings.Join(append([]string{t.GOOS + "/" + t.GOARCH}, t.BuildTags...), ",")+"\n")
+	}
+	for _, tag := range ctx.IgnoreTags {
+		lines = append(lines, "ignore-tag "+tag+"\n")
+	}
+	return hashLines(lines)
+}
+
+// copyUnchanged copies vendored unchanged deps from vd to staging directory.
+func copyUnchanged(vd, staging string, unchanged map[string]lockEntry) error {
+	var imps []string
+	for imp := range unchanged {
+		imps = append(imps, imp)
+	}
+	for _, imp := range imps {
+		if err := copyTree(filepath.Join(vd, imp), filepath.Join(staging, imp), nestedRoots(vd, imp, imps)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refetchFunc returns function for collectAllDeps which clones again a
+// skipped unchanged dependency, if its vendored copy lacks imported package.
+// That happens when the package was cleaned as unused on previous run.
+// Cloned dependencies are recorded to lock.
+func refetchFunc(wd, staging string, skipped []depEntry, lock *[]lockEntry) func(string) (*build.Package, error) {
+	return func(imp string) (*build.Package, error) {
+		for i, d := range skipped {
+			if imp != d.importPath && !strings.HasPrefix(imp, d.importPath+"/") {
+				continue
+			}
+			skipped = append(skipped[:i:i], skipped[i+1:]...)
+			log.Printf("\tVendored %s lacks package %s, clone it again", d.importPath, imp)
+			l, err := cloneDep(staging, d)
+			if err != nil {
+				return nil, err
+			}
+			*lock = mergeLock(*lock, []lockEntry{l})
+			break
+		}
+		return importVendored(wd, imp)
+	}
+}
+
+// importVendored imports package like collectAllDeps does without download
+// function: packages outside of wd are reported as not vendored.
+func importVendored(wd, imp string) (*build.Package, error) {
+	pkg, err := ctx.Import(imp, wd, 0)
+	if err != nil {
+		if _, ok := err.(*build.MultiplePackageError); ok {
+			return pkg, nil
+		}
+		return pkg, err
+	}
+	if !strings.HasPrefix(pkg.Dir, wd) {
+		Warnf("dependency is not vendored: %s", imp)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestUnchangedDeps(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-unchanged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	writeFiles(t, vd, map[string]string{"github.com/foo/bar/bar.go": "package bar\n"})
+	h, err := hashDir(filepath.Join(vd, "github.com", "foo", "bar"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const commit = "03a4d9dcf2f92eae8e90ed42aa2656f63fdd0b14"
+	d := depEntry{importPath: "github.com/foo/bar", rev: commit}
+	l := lockEntry{importPath: d.importPath, rev: commit, commit: commit, vcs: "git", repo: "https://github.com/foo/bar", hash: h, clean: cleanInputs(d)}
+
+	short := d
+	short.rev = commit[:7]
+	shortLock := l
+	shortLock.rev = short.rev
+	kept := d
+	kept.keep = []string{"testdata"}
+	cases := []struct {
+		name      string
+		d         depEntry
+		l         lockEntry
+		whitelist regexpSlice
+		unchanged bool
+	}{
+		{name: "same", d: d, l: l, unchanged: true},
+		{name: "short hash", d: short, l: shortLock, unchanged: true},
+		{name: "attribute", d: kept, l: l},
+		{name: "whitelist", d: d, l: l, whitelist: regexpSlice{regexp.MustCompile("testdata")}},
+		{name: "no settings", d: d, l: lockEntry{importPath: d.importPath, rev: commit, commit: commit, hash: h}},
+	}
+	for _, c := range cases {
+		cleanWhitelist = c.whitelist
+		unchanged, err := unchangedDeps(vd, []depEntry{c.d}, []lockEntry{c.l})
+		cleanWhitelist = nil
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := unchanged[d.importPath]; ok != c.unchanged {
+			t.Fatalf("%s: expected unchanged %v, got %v", c.name, c.unchanged, ok)
+		}
+	}
+}
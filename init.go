@@ -3,8 +3,6 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"os/exec"
 	"strings"
 
@@ -66,9 +64,11 @@ func getRev(v *godl.VCS) (string, error) {
 }
 
 func writeConfig(deps []depEntry, cfgFile string) error {
-	var lines []string
-	for _, d := range deps {
-		lines = append(lines, d.String())
+	c := newConfig(deps)
+	if len(ignoreTags) > 0 {
+		// later runs ignore the same tags without the flag
+		d := configLine{text: directivePrefix + "ignore-tags " + strings.Join(ignoreTags, ",")}
+		c.lines = append([]configLine{d}, c.lines...)
 	}
-	return ioutil.WriteFile(cfgFile, []byte(strings.Join(lines, "")), os.FileMode(0666))
+	return c.write(cfgFile)
 }
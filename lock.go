@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const lockFile = "vendor.sum"
+
+const lockHeader = "# This file is generated by vndr from vendor.conf. DO NOT EDIT.\n"
+
+// lockEntry describes what was actually vendored for a vendor.conf entry.
+type lockEntry struct {
+	importPath string
+	rev        string // revision as written in vendor.conf
+	commit     string // revision resolved by the vcs
+	vcs        string
+	repo       string // repository URL used for download
+	hash       string // hash of the cleaned vendor/<importPath> tree
+	clean      string // hash of settings the tree was cleaned with, see cleanInputs
+}
+
+func (l lockEntry) String() string {
+	s := fmt.Sprintf("%s %s %s %s %s %s", l.importPath, l.rev, l.commit, l.vcs, l.repo, l.hash)
+	if l.clean != "" {
+		s += " " + l.clean
+	}
+	return s + "\n"
+}
+
+func parseLock(r io.Reader) ([]lockEntry, error) {
+	var entries []lockEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		ln := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(ln, "#") || ln == "" {
+			continue
+		}
+		parts := strings.Fields(ln)
+		// settings of cleaning are missing in locks of older versions
+		if len(parts) != 6 && len(parts) != 7 {
+			return nil, fmt.Errorf("invalid lock format: %s", ln)
+		}
+		l := lockEntry{
+			importPath: parts[0],
+			rev:        parts[1],
+			commit:     parts[2],
+			vcs:        parts[3],
+			repo:       parts[4],
+			hash:       parts[5],
+		}
+		if len(parts) == 7 {
+			l.clean = parts[6]
+		}
+		entries = append(entries, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readLock reads lock file. Missing file is not an error, nil is returned
+// instead.
+func readLock(lockFile string) ([]lockEntry, error) {
+	f, err := os.Open(lockFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to open lock file: %v", err)
+	}
+	defer f.Close()
+	entries, err := parseLock(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse lock file: %v", err)
+	}
+	return entries, nil
+}
+
+func writeLock(entries []lockEntry, lockFile string) error {
+	sorted := append([]lockEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].importPath < sorted[j].importPath })
+	lines := []string{lockHeader}
+	for _, l := range sorted {
+		lines = append(lines, l.String())
+	}
+	return ioutil.WriteFile(lockFile, []byte(strings.Join(lines, "")), os.FileMode(0666))
+}
+
+// mergeLock replaces entries of old with entries from updated which have the
+// same import path and appends the rest.
+func mergeLock(old, updated []lockEntry) []lockEntry {
+	idx := make(map[string]int)
+	var merged []lockEntry
+	for _, l := range old {
+		idx[l.importPath] = len(merged)
+		merged = append(merged, l)
+	}
+	for _, l := range updated {
+		if i, ok := idx[l.importPath]; ok {
+			merged[i] = l
+			continue
+		}
+		idx[l.importPath] = len(merged)
+		merged = append(merged, l)
+	}
+	return merged
+}
+
+// nestedRoots returns directories of roots from importPaths which are inside of
+// root directory, i.e. github.com/coreos/go-systemd/v22 for
+// github.com/coreos/go-systemd. They belong to another vendor.conf entry.
+func nestedRoots(vd, root string, importPaths []string) map[string]bool {
+	nested := make(map[string]bool)
+	for _, imp := range importPaths {
+		if strings.HasPrefix(imp, root+"/") {
+			nested[filepath.Join(vd, imp)] = true
+		}
+	}
+	return nested
+}
+
+// hashLock fills hashes of lock entries from current content of vendor
+// directory.
+func hashLock(vd string, entries []lockEntry) error {
+	var imps []string
+	for _, l := range entries {
+		imps = append(imps, l.importPath)
+	}
+	for i, l := range entries {
+		h, err := hashDir(filepath.Join(vd, l.importPath), nestedRoots(vd, l.importPath, imps))
+		if err != nil {
+			return err
+		}
+		entries[i].hash = h
+	}
+	return nil
+}
+
+// hashDir returns deterministic hash of directory content. Directories from
+// exclude are skipped. Missing directory is hashed as an empty one.
+// The hash is sha256 of the sorted list of lines
+// "<sha256 of file> <path relative to dir>".
+func hashDir(dir string, exclude map[string]bool) (string, error) {
+	var lines []string
+	err := filepath.Walk(dir, func(path string, i os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if i.IsDir() {
+			if exclude[path] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		fh, err := hashFile(path, i)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%x  %s\n", fh, filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hashLines(lines), nil
+}
+
+// emptyHash is the hash of empty or missing directory, i.e. of unused package
+// which was removed completely.
+var emptyHash = hashLines(nil)
+
+func hashLines(lines []string) string {
+	sort.Strings(lines)
+	h := sha256.New()
+	for _, ln := range lines {
+		io.WriteString(h, ln)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func hashFile(path string, i os.FileInfo) ([]byte, error) {
+	h := sha256.New()
+	if i.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(h, target)
+		return h.Sum(nil), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLockRoundTrip(t *testing.T) {
+	entries := []lockEntry{
+		{
+			importPath: "github.com/foo/bar",
+			rev:        "v1.0.0",
+			commit:     "03a4d9dcf2f92eae8e90ed42aa2656f63fdd0b14",
+			vcs:        "git",
+			repo:       "https://github.com/foo/bar",
+			hash:       "h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+			clean:      "h1:J+5JkB27ySXyCa1Ifx+Uy+S91DQL4JweLewjcI43oDA=",
+		},
+		{
+			importPath: "github.com/baz/qux",
+			rev:        "master",
+			commit:     "c5613b87bafaaf105fd3857dcae7ef23c931feec",
+			vcs:        "git",
+			repo:       "https://github.com/LK4D4/qux.git",
+			hash:       "h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+		},
+	}
+	dir, err := ioutil.TempDir("", "vndr-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	lf := filepath.Join(dir, lockFile)
+	if err := writeLock(entries, lf); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := readLock(lf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []lockEntry{entries[1], entries[0]}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Fatalf("expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	root := filepath.Join(vd, "github.com", "foo", "bar")
+	writeFiles(t, root, map[string]string{"bar.go": "package bar\n"})
+	h1, err := hashDir(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, root, map[string]string{"v2/bar.go": "package bar\n"})
+	h2, err := hashDir(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Fatal("hash must change after adding a file")
+	}
+	h3, err := hashDir(root, nestedRoots(vd, "github.com/foo/bar", []string{"github.com/foo/bar", "github.com/foo/bar/v2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 != h3 {
+		t.Fatalf("nested roots must be excluded from hash: %s != %s", h1, h3)
+	}
+	empty, err := hashDir(filepath.Join(vd, "missing"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty == h1 {
+		t.Fatal("missing directory must not have the same hash as non-empty one")
+	}
+}
@@ -26,6 +26,16 @@ var (
 	verbose        bool
 	cleanWhitelist regexpSlice
 	strict         bool
+	cacheDir       string
+	offline        bool
+	dryRun         bool
+	transitiveMode string
+	updateBranch   string
+	jsonOutput     bool
+	reportClean    bool
+	graphRepos     bool
+	platforms      platformSlice
+	ignoreTags     tagList
 )
 
 type regexpSlice []*regexp.Regexp
@@ -50,6 +60,49 @@ func (rs *regexpSlice) String() string {
 	return fmt.Sprintf("%v", exps)
 }
 
+// platformSlice is a list of platforms in the form os/arch[,tag...].
+type platformSlice []build.Target
+
+var _ flag.Value = new(platformSlice)
+
+func (ps *platformSlice) Set(value string) error {
+	parts := strings.Split(value, ",")
+	osArch := strings.Split(parts[0], "/")
+	if len(osArch) != 2 || !build.KnownPlatform(osArch[0], osArch[1]) {
+		return fmt.Errorf("invalid platform %q, use os/arch[,tag...]", value)
+	}
+	t := build.Target{GOOS: osArch[0], GOARCH: osArch[1]}
+	for _, tag := range parts[1:] {
+		if tag != "" {
+			t.BuildTags = append(t.BuildTags, tag)
+		}
+	}
+	*ps = append(*ps, t)
+	return nil
+}
+
+func (ps *platformSlice) String() string {
+	var platforms []string
+	for _, t := range *ps {
+		platforms = append(platforms, strings.Join(append([]string{t.GOOS + "/" + t.GOARCH}, t.BuildTags...), ","))
+	}
+	return fmt.Sprintf("%v", platforms)
+}
+
+// tagList is a list of build tags set by comma separated lists.
+type tagList []string
+
+var _ flag.Value = new(tagList)
+
+func (tl *tagList) Set(value string) error {
+	*tl = append(*tl, splitList(value)...)
+	return nil
+}
+
+func (tl *tagList) String() string {
+	return strings.Join(*tl, ",")
+}
+
 func (rs *regexpSlice) matchString(str string) bool {
 	for _, regex := range *rs {
 		if regex.MatchString(str) {
@@ -62,23 +115,53 @@ func (rs *regexpSlice) matchString(str string) bool {
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "%s [[import path] [revision]] [repository]\n%s init\n", os.Args[0], os.Args[0])
+		fmt.Fprintf(os.Stderr, "%s [[import path] [revision]] [repository]\n%s init\n%s verify\n%s update [import path...]\n%s outdated\n%s graph\n%s why <import path>\n%s import-gomod [go.mod]\n%s export-gomod\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.BoolVar(&verbose, "verbose", false, "shows all warnings")
 	flag.Var(&cleanWhitelist, "whitelist", "regular expressions to whitelist for cleaning phase of vendoring, relative to the vendor/ directory")
 	flag.BoolVar(&strict, "strict", false, "checking mode. treat non-trivial warning as an error")
+	flag.StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "directory for caching downloaded repositories, empty value disables cache")
+	flag.BoolVar(&offline, "offline", false, "vendor from cache only, without network access")
+	flag.BoolVar(&dryRun, "dry-run", false, "print what would be cloned and which vendor directories would be changed, without changing anything")
+	flag.BoolVar(&jsonOutput, "json", false, "print reports in JSON format")
+	flag.Var(&ignoreTags, "ignore-tags", "comma separated build tags, files guarded by them are ignored like files with \"ignore\" tag; can be repeated")
+	flag.Var(&platforms, "platform", "platform os/arch[,tag...] which vendored packages are built for, files and packages used only on other platforms are removed; can be repeated")
+	flag.BoolVar(&graphRepos, "repos", false, "print graph of repositories instead of packages")
+	flag.BoolVar(&reportClean, "report", false, "print paths removed from vendor dir by cleaning and rules which removed them")
+	flag.StringVar(&updateBranch, "branch", "", "update dependencies to the head of this branch instead of the newest tag")
+	flag.StringVar(&transitiveMode, "transitive", "", "resolve dependencies missing in vendor.conf by revisions pinned in vendor.conf and go.mod of vendored dependencies: \"suggest\" reports them, \"add\" vendors them and adds them to vendor.conf")
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "vndr")
 }
 
 func validateArgs() {
-	if len(flag.Args()) > 3 {
+	if len(flag.Args()) > 3 && flag.Arg(0) != "update" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if (flag.Arg(0) == "init" || flag.Arg(0) == "verify" || flag.Arg(0) == "outdated" || flag.Arg(0) == "graph" || flag.Arg(0) == "export-gomod") && len(flag.Args()) > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.Arg(0) == "why" && len(flag.Args()) != 2 {
 		flag.Usage()
 		os.Exit(2)
 	}
-	if flag.Arg(0) == "init" && len(flag.Args()) > 1 {
+	if flag.Arg(0) == "import-gomod" && len(flag.Args()) > 2 {
 		flag.Usage()
 		os.Exit(2)
 	}
+	if transitiveMode != "" && transitiveMode != transitiveSuggest && transitiveMode != transitiveAdd {
+		fmt.Fprintf(os.Stderr, "invalid value %q for flag -transitive, use %q or %q\n", transitiveMode, transitiveSuggest, transitiveAdd)
+		os.Exit(2)
+	}
 }
 
 func checkUnused(deps []depEntry, vd string) {
@@ -104,15 +187,17 @@ func checkLicense(deps []depEntry, vd string) {
 				licenseFiles++
 			}
 		}
-		if licenseFiles == 0 && verbose {
+		if licenseFiles == 0 && d.license == "" && verbose {
 			log.Printf("WARNING(verbose): package %s may lack license information", d.importPath)
 		}
 	}
 }
 
 func mergeDeps(root string, deps []depEntry) depEntry {
-	merged := depEntry{importPath: root}
-	merged.rev = deps[0].rev
+	// attributes of the first dependency are kept
+	merged := deps[0]
+	merged.importPath = root
+	merged.repoPath = ""
 	for _, d := range deps {
 		if d.repoPath != "" {
 			merged.repoPath = d.repoPath
@@ -137,6 +222,10 @@ func rootImport(dep depEntry) (string, error) {
 		// A custom repository URL is passed. Skip validating if the package
 		// name is at the root of the repository, and assume it is.
 		root = dep.importPath
+	} else if _, ok := lockedRepos[dep.importPath]; ok && offline {
+		// Locked import paths are roots and the network is not available
+		// for analyzing them.
+		root = dep.importPath
 	} else {
 		// Analyze the import path to determine the version control system,
 		// repository, and the import path for the root of the repository.
@@ -163,7 +252,7 @@ func validateDeps(deps []depEntry) error {
 		}
 		roots[root] = append(roots[root], d)
 	}
-	var newDeps []depEntry
+	newDeps := make(map[string]depEntry)
 	var invalid bool
 	for _, r := range rootsOrder {
 		rootDeps := roots[r]
@@ -172,10 +261,11 @@ func validateDeps(deps []depEntry) error {
 			if d.importPath != r && !versioned.IsVersioned(d.importPath) {
 				Warnf("package %s is not root import, should be %s", d.importPath, r)
 				invalid = true
-				newDeps = append(newDeps, depEntry{importPath: r, rev: d.rev, repoPath: d.repoPath})
+				d.importPath = r
+				newDeps[r] = d
 				continue
 			}
-			newDeps = append(newDeps, d)
+			newDeps[r] = d
 			continue
 		}
 		invalid = true
@@ -184,27 +274,71 @@ func validateDeps(deps []depEntry) error {
 			imps = append(imps, d.importPath)
 		}
 		Warnf("packages '%s' has same root import %s", strings.Join(imps, ", "), r)
-		newDeps = append(newDeps, mergeDeps(r, rootDeps))
+		newDeps[r] = mergeDeps(r, rootDeps)
 	}
 	if !invalid {
 		return nil
 	}
+	if dryRun {
+		return errors.New("There were some validation errors")
+	}
+	// suggested config keeps comments and formatting of config, the first
+	// line of each root is replaced by fixed dependency, the rest are dropped
+	cfg, err := readConfig(configFile)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var lines []configLine
+	for _, l := range cfg.lines {
+		if l.dep != nil {
+			root, err := rootImport(*l.dep)
+			if err != nil {
+				return err
+			}
+			if seen[root] {
+				continue
+			}
+			seen[root] = true
+			l = l.with(newDeps[root])
+		}
+		lines = append(lines, l)
+	}
+	cfg.lines = lines
 	tmpConfig := configFile + ".tmp"
-	if err := writeConfig(newDeps, tmpConfig); err != nil {
+	if err := cfg.write(tmpConfig); err != nil {
 		return err
 	}
 	Warnf("suggested vendor.conf is written to %s, use diff and common sense before using it", tmpConfig)
 	return errors.New("There were some validation errors")
 }
 
-func getDeps() ([]depEntry, error) {
-	cfg, err := os.Open(configFile)
+// configIgnoreTags returns tags of ignore-tags directives of config file. It
+// returns nil if there is no config file.
+func configIgnoreTags() ([]string, error) {
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+	cfg, err := readConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.directive("ignore-tags"), nil
+}
+
+// readDeps reads config file without any validation.
+func readDeps() ([]depEntry, error) {
+	cfg, err := readConfig(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to open config file: %v", err)
+		return nil, err
 	}
-	deps, err := parseDeps(cfg)
+	return cfg.deps(), nil
+}
+
+func getDeps() ([]depEntry, error) {
+	deps, err := readDeps()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to parse config: %v", err)
+		return nil, err
 	}
 	if err := validateDeps(deps); err != nil {
 		return nil, err
@@ -241,6 +375,23 @@ func main() {
 	}()
 	flag.Parse()
 	validateArgs()
+	if offline && cacheDir == "" {
+		log.Fatal("Offline mode requires cache directory")
+	}
+	godl.CacheDir = cacheDir
+	godl.Offline = offline
+	ctx.Targets = platforms
+	confTags, err := configIgnoreTags()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, tag := range append(ignoreTags[:len(ignoreTags):len(ignoreTags)], confTags...) {
+		log.Printf("\tIgnoring files with tag %q", tag)
+		ctx.IgnoreTags = append(ctx.IgnoreTags, tag)
+	}
+	if reportClean {
+		cleanReport = &removalLog{}
+	}
 	gp, err := getGOPATH()
 	if err != nil {
 		log.Fatal(err)
@@ -251,9 +402,14 @@ func main() {
 	var init bool
 	if flag.Arg(0) == "init" {
 		init = true
+		if dryRun {
+			log.Fatal("Dry run is not supported for initialization")
+		}
 		_, cerr := os.Stat(configFile)
 		_, verr := os.Stat(vendorDir)
-		if cerr == nil || verr == nil {
+		// vendor dir of govendor is allowed, it has manifest with revisions
+		_, gerr := os.Stat(filepath.Join(vendorDir, "vendor.json"))
+		if cerr == nil || (verr == nil && gerr != nil) {
 			log.Fatal("There must not be vendor dir and vendor.conf file for initialization")
 		}
 	}
@@ -267,60 +423,254 @@ func main() {
 		log.Fatalf("Error getting working directory after evalsymlinks: %v", err)
 	}
 	vd := filepath.Join(wd, vendorDir)
+	staging := filepath.Join(wd, stagingDir)
+	switch flag.Arg(0) {
+	case "verify":
+		if err := verify(vd); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "outdated":
+		lock, err := readLock(lockFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, l := range lock {
+			lockedRepos[l.importPath] = l
+		}
+		deps, err := readDeps()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := outdated(os.Stdout, deps, jsonOutput); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "graph":
+		g, err := vendorGraph(wd, graphRepos)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := printGraph(os.Stdout, g, jsonOutput); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "why":
+		g, err := vendorGraph(wd, false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := why(os.Stdout, g, strings.TrimRight(flag.Arg(1), "/"), jsonOutput); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "import-gomod":
+		goModFile := flag.Arg(1)
+		if goModFile == "" {
+			goModFile = "go.mod"
+		}
+		if err := importGoMod(goModFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "export-gomod":
+		if err := exportGoMod(wd, vd); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	log.Println("Collecting initial packages")
 	initPkgs, err := collectPkgs(wd)
 	if err != nil {
 		log.Fatalf("Error collecting initial packages: %v", err)
 	}
+	// new vendor tree is built in staging directory, vendor directory stays
+	// untouched on any error. Dry run returns before staging directory is
+	// used and changes nothing.
+	if !dryRun {
+		if err := os.RemoveAll(staging); err != nil {
+			log.Fatal(err)
+		}
+	}
+	fatal := func(v ...interface{}) {
+		os.RemoveAll(staging)
+		log.Fatal(v...)
+	}
+	ctx.VendorDir = stagingDir
 	// variables for init
 	var dlFunc func(string) (*build.Package, error)
 	var deps []depEntry
+	var lock []lockEntry
+	// all dependencies from config, deps can be only flag dependency
+	var confDeps []depEntry
+	// dependencies with new revisions, which are written to config after
+	// vendoring
+	var updated []depEntry
+	var tr *transitive
+	if transitiveMode != "" {
+		tr = newTransitive(transitiveMode)
+	}
 	if !init {
 		log.Println("Download dependencies")
+		oldLock, err := readLock(lockFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, l := range oldLock {
+			lockedRepos[l.importPath] = l
+		}
 		cfgDeps, err := getDeps()
 		if err != nil {
 			log.Fatal(err)
 		}
-		if len(flag.Args()) != 0 {
+		confDeps = cfgDeps
+		whole := len(flag.Args()) == 0
+		if flag.Arg(0) == "update" {
+			log.Println("Looking for updates")
+			var all []depEntry
+			all, updated, err = updateDeps(cfgDeps, flag.Args()[1:], updateBranch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(updated) == 0 {
+				log.Println("Dependencies are up to date")
+				return
+			}
+			confDeps = all
+			cfgDeps = updated
+		} else if !whole {
 			flagDep, err := getFlagDep(cfgDeps)
 			if err != nil {
 				log.Fatal(err)
 			}
 			cfgDeps = []depEntry{flagDep}
-		} else {
+		}
+		// on whole cycle dependencies vendored exactly as lock describes are
+		// not cloned again
+		unchanged := map[string]lockEntry{}
+		if whole {
+			unchanged, err = unchangedDeps(vd, cfgDeps, oldLock)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if dryRun {
+			if err := printPlan(vd, cfgDeps, unchanged, whole); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if whole {
 			log.Println("Starting whole vndr cycle because no package specified")
-			if len(cleanWhitelist) > 0 {
-				for _, regex := range cleanWhitelist {
-					log.Printf("\tIgnoring paths matching %q", regex.String())
-				}
-				if err := cleanVendor(vd, nil); err != nil {
-					log.Fatal(err)
-				}
-			} else {
-				if err := os.RemoveAll(vd); err != nil {
-					log.Fatal(err)
-				}
+		}
+		if !whole || len(cleanWhitelist) > 0 {
+			if err := copyTree(vd, staging, nil); err != nil {
+				fatal(err)
 			}
 		}
+		if whole && len(cleanWhitelist) > 0 {
+			for _, regex := range cleanWhitelist {
+				log.Printf("\tIgnoring paths matching %q", regex.String())
+			}
+			// paths of the old vendor dir are not reported
+			report := cleanReport
+			cleanReport = nil
+			if err := cleanVendor(staging, nil, nil); err != nil {
+				fatal(err)
+			}
+			cleanReport = report
+		}
+		if err := copyUnchanged(vd, staging, unchanged); err != nil {
+			fatal(err)
+		}
+		var cloneDeps, skipped []depEntry
+		for _, d := range cfgDeps {
+			if _, ok := unchanged[d.importPath]; ok {
+				log.Printf("\tSkip unchanged %s, revision %s", d.importPath, d.rev)
+				skipped = append(skipped, d)
+				continue
+			}
+			cloneDeps = append(cloneDeps, d)
+		}
 		startDownload := time.Now()
-		if err := cloneAll(vd, cfgDeps); err != nil {
-			log.Fatal(err)
+		locked, err := cloneAll(staging, cloneDeps)
+		if err != nil {
+			fatal(err)
+		}
+		for _, d := range skipped {
+			locked = append(locked, unchanged[d.importPath])
+		}
+		if !whole {
+			locked = mergeLock(oldLock, locked)
 		}
 		deps = cfgDeps
+		lock = locked
+		if len(skipped) > 0 {
+			dlFunc = refetchFunc(wd, staging, skipped, &lock)
+		}
+		if tr != nil {
+			for _, d := range confDeps {
+				if err := tr.read(staging, d.importPath); err != nil {
+					fatal(err)
+				}
+			}
+			dlFunc = tr.dlFunc(wd, staging, confDeps, &lock, dlFunc)
+		}
 		log.Printf("Dependencies downloaded. Download time: %v", time.Since(startDownload))
 	} else {
+		// revisions pinned by manifests of other vendoring tools are used
+		// instead of default branches
+		pinned := pins{}
+		pinnedDeps, manifestFiles, err := readManifests(wd, manifests)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, f := range manifestFiles {
+			log.Printf("\tUsing revisions from %s", f)
+		}
+		pinned.add(pinnedDeps)
+		depManifests := manifests
+		if transitiveMode != "" {
+			depManifests = append(depManifests[:len(depManifests):len(depManifests)], nestedManifests...)
+		}
 		dlFunc = func(imp string) (*build.Package, error) {
-			vcs, err := godl.Download(imp, "", filepath.Join(wd, vendorDir), "")
+			root, err := rootImport(depEntry{importPath: imp})
+			if err != nil {
+				return nil, err
+			}
+			pin, ok := pinned.lookup(imp, root)
+			dlImp := imp
+			if pin.repoPath != "" {
+				// custom repository requires root import path
+				dlImp = pin.importPath
+			}
+			vcs, err := godl.Download(dlImp, pin.repoPath, staging, pin.rev)
 			if err != nil {
 				return nil, err
 			}
-			rev, err := getRev(vcs)
+			commit, err := getRev(vcs)
 			if err != nil {
 				return nil, err
 			}
+			rev := commit
+			if ok {
+				rev = pin.rev
+			}
 			log.Printf("\tDownloaded %s, revision %s", imp, rev)
-			deps = append(deps, depEntry{importPath: vcs.ImportPath, rev: rev})
+			d := depEntry{importPath: vcs.ImportPath, rev: rev, repoPath: pin.repoPath}
+			deps = append(deps, d)
+			lock = append(lock, lockEntry{importPath: vcs.ImportPath, rev: rev, commit: commit, vcs: vcs.Type, repo: vcs.Repo, clean: cleanInputs(d)})
+			// manifests of dependency pin its own dependencies, revisions
+			// pinned before have precedence
+			depPinned, depFiles, err := readManifests(vcs.Root, depManifests)
+			if err != nil {
+				Warnf("%s: %v", vcs.ImportPath, err)
+			}
+			for _, f := range depFiles {
+				log.Printf("\tUsing revisions from %s of %s", f, vcs.ImportPath)
+			}
+			pinned.add(depPinned)
 
 			pkg, err := ctx.Import(imp, wd, 0)
 			if _, ok := err.(*build.MultiplePackageError); ok {
@@ -331,15 +681,37 @@ func main() {
 		log.Println("Start vendoring initialization")
 	}
 	log.Println("Collecting all dependencies")
-	pkgs, err := collectAllDeps(wd, dlFunc, initPkgs...)
+	pkgs, err := collectAllDeps(wd, dlFunc, subpackageImports(confDeps), initPkgs...)
 	if err != nil {
-		log.Fatalf("Error on collecting all dependencies: %v", err)
+		fatal(fmt.Sprintf("Error on collecting all dependencies: %v", err))
+	}
+	if !init {
+		if tr != nil && tr.add {
+			confDeps = append(confDeps[:len(confDeps):len(confDeps)], tr.resolved...)
+		}
+		if err := reportConflicts(os.Stderr, staging, confDeps, lock); err != nil {
+			fatal(err)
+		}
 	}
 	log.Println("Clean vendor dir from unused packages")
 	for _, regex := range cleanWhitelist {
 		log.Printf("\tIgnoring paths matching %q", regex.String())
 	}
-	if err := cleanVendor(vd, pkgs); err != nil {
+	if err := cleanVendor(staging, pkgs, confDeps); err != nil {
+		fatal(err)
+	}
+	if cleanReport != nil {
+		if err := cleanReport.print(os.Stdout, jsonOutput); err != nil {
+			fatal(err)
+		}
+	}
+	if err := swapVendor(staging, vd); err != nil {
+		fatal(fmt.Sprintf("Error replacing vendor dir: %v", err))
+	}
+	if err := hashLock(vd, lock); err != nil {
+		log.Fatalf("Error hashing vendor dir: %v", err)
+	}
+	if err := writeLock(lock, lockFile); err != nil {
 		log.Fatal(err)
 	}
 	if init {
@@ -348,6 +720,19 @@ func main() {
 		}
 		log.Println("Vendor initialized and result is in", configFile)
 	} else {
+		if len(updated) > 0 {
+			if err := setConfigRevs(configFile, updated); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("Updated %d dependencies in %s", len(updated), configFile)
+		}
+		if tr != nil && tr.add && len(tr.resolved) > 0 {
+			if err := appendConfig(tr.resolved, configFile); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("Added %d transitive dependencies to %s", len(tr.resolved), configFile)
+			deps = append(deps, tr.resolved...)
+		}
 		checkUnused(deps, vd)
 	}
 	checkLicense(deps, vd)
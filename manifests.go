@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// manifest is a lock file of other vendoring tool, which pins revisions of
+// dependencies.
+type manifest struct {
+	file  string
+	parse func(r io.Reader) ([]depEntry, error)
+}
+
+var manifests = []manifest{
+	{filepath.Join("Godeps", "Godeps.json"), parseGodeps},
+	{"glide.lock", parseGlideLock},
+	{"Gopkg.lock", parseGopkgLock},
+	{filepath.Join(vendorDir, "vendor.json"), parseGovendor},
+	{"trash.yml", parseTrash},
+}
+
+// readManifests returns dependencies pinned by manifests from ms found in dir
+// and names of these manifests.
+func readManifests(dir string, ms []manifest) ([]depEntry, []string, error) {
+	var deps []depEntry
+	var found []string
+	for _, m := range ms {
+		f, err := os.Open(filepath.Join(dir, m.file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+		ds, err := m.parse(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to parse %s: %v", m.file, err)
+		}
+		deps = append(deps, ds...)
+		found = append(found, m.file)
+	}
+	return deps, found, nil
+}
+
+// pins are pinned dependencies keyed by import path.
+type pins map[string]depEntry
+
+// add adds deps to pins, already pinned import paths are not changed.
+func (p pins) add(deps []depEntry) {
+	for _, d := range deps {
+		if d.rev == "" {
+			continue
+		}
+		if _, ok := p[d.importPath]; !ok {
+			p[d.importPath] = d
+		}
+	}
+}
+
+// lookup returns pinned dependency with the longest import path which is imp
+// or its parent. Otherwise any package of repository with root import path
+// is used, some manifests pin packages instead of repositories.
+func (p pins) lookup(imp, root string) (depEntry, bool) {
+	for path := imp; path != "." && path != "/"; path = filepath.ToSlash(filepath.Dir(path)) {
+		if d, ok := p[path]; ok {
+			return d, true
+		}
+	}
+	var paths []string
+	for path := range p {
+		if strings.HasPrefix(path, root+"/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return depEntry{}, false
+	}
+	sort.Strings(paths)
+	return p[paths[0]], true
+}
+
+// manifestRepo converts repository from manifest to URL with scheme:
+// git@github.com:LK4D4/vndr and github.com/LK4D4/vndr become
+// https://github.com/LK4D4/vndr.
+func manifestRepo(repo string) string {
+	if repo == "" || strings.Contains(repo, "://") {
+		return repo
+	}
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = strings.Replace(repo[i+1:], ":", "/", 1)
+	}
+	return "https://" + repo
+}
+
+// parseGodeps parses Godeps/Godeps.json of godep.
+func parseGodeps(r io.Reader) ([]depEntry, error) {
+	var m struct {
+		Deps []struct {
+			ImportPath string
+			Rev        string
+		}
+	}
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	var deps []depEntry
+	for _, d := range m.Deps {
+		deps = append(deps, depEntry{importPath: d.ImportPath, rev: d.Rev})
+	}
+	return deps, nil
+}
+
+// parseGovendor parses vendor/vendor.json of govendor.
+func parseGovendor(r io.Reader) ([]depEntry, error) {
+	var m struct {
+		Package []struct {
+			Path     string `json:"path"`
+			Revision string `json:"revision"`
+		} `json:"package"`
+	}
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	var deps []depEntry
+	for _, p := range m.Package {
+		deps = append(deps, depEntry{importPath: p.Path, rev: p.Revision})
+	}
+	return deps, nil
+}
+
+// parseGlideLock parses glide.lock, both imports and testImports.
+func parseGlideLock(r io.Reader) ([]depEntry, error) {
+	return parseYAMLDeps(r, "name")
+}
+
+// parseTrash parses trash.yml.
+func parseTrash(r io.Reader) ([]depEntry, error) {
+	return parseYAMLDeps(r, "package")
+}
+
+// parseYAMLDeps parses YAML lists of dependencies like
+//
+//	import:
+//	- package: github.com/LK4D4/vndr
+//	  version: v0.1.1
+//	  repo: https://github.com/LK4D4/vndr.git
+//
+// where nameKey is the key of the first line of dependency. It is not a YAML
+// parser, only the layout written by glide and trash is supported.
+func parseYAMLDeps(r io.Reader, nameKey string) ([]depEntry, error) {
+	var deps []depEntry
+	var cur *depEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		ln := s.Text()
+		if i := strings.Index(ln, " #"); i >= 0 {
+			ln = ln[:i]
+		}
+		if strings.TrimSpace(ln) == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		item := strings.HasPrefix(strings.TrimSpace(ln), "- ")
+		if !item && ln[0] != ' ' && ln[0] != '\t' {
+			// top level key ends list
+			cur = nil
+			continue
+		}
+		key, value, ok := yamlKeyValue(ln)
+		if !ok {
+			continue
+		}
+		if item && key == nameKey {
+			deps = append(deps, depEntry{importPath: value})
+			cur = &deps[len(deps)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch key {
+		case "version":
+			cur.rev = value
+		case "repo":
+			cur.repoPath = manifestRepo(value)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+func yamlKeyValue(ln string) (string, string, bool) {
+	ln = strings.TrimPrefix(strings.TrimSpace(ln), "- ")
+	i := strings.Index(ln, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	key, value := strings.TrimSpace(ln[:i]), strings.TrimSpace(ln[i+1:])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, true
+}
+
+// parseGopkgLock parses [[projects]] tables of Gopkg.lock of dep.
+func parseGopkgLock(r io.Reader) ([]depEntry, error) {
+	var deps []depEntry
+	var cur *depEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		ln := strings.TrimSpace(s.Text())
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		if strings.HasPrefix(ln, "[") {
+			cur = nil
+			if ln == "[[projects]]" {
+				deps = append(deps, depEntry{})
+				cur = &deps[len(deps)-1]
+			}
+			continue
+		}
+		i := strings.Index(ln, "=")
+		if cur == nil || i < 0 {
+			continue
+		}
+		key, value := strings.TrimSpace(ln[:i]), strings.TrimSpace(ln[i+1:])
+		if !strings.HasPrefix(value, `"`) {
+			continue
+		}
+		value, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %q: %v", ln, err)
+		}
+		switch key {
+		case "name":
+			cur.importPath = value
+		case "revision":
+			cur.rev = value
+		case "source":
+			cur.repoPath = manifestRepo(value)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifests(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected []depEntry
+	}{
+		{
+			name: "Godeps.json",
+			data: `{
+	"ImportPath": "github.com/example/project",
+	"GoVersion": "go1.8",
+	"Deps": [
+		{
+			"ImportPath": "github.com/pkg/errors",
+			"Comment": "v0.8.0",
+			"Rev": "645ef00459ed84a119197bfb8d8205042c6df63d"
+		},
+		{
+			"ImportPath": "golang.org/x/net/context",
+			"Rev": "f2499483f923065a842d38eb4c7f1927e6fc6e6d"
+		}
+	]
+}`,
+			expected: []depEntry{
+				{importPath: "github.com/pkg/errors", rev: "645ef00459ed84a119197bfb8d8205042c6df63d"},
+				{importPath: "golang.org/x/net/context", rev: "f2499483f923065a842d38eb4c7f1927e6fc6e6d"},
+			},
+		},
+		{
+			name: "glide.lock",
+			data: `hash: 0f5ab3c5bc2f1f1e4ec4a5f0c4b0a2cbf5d8d2b1d5f6a1e7f5e3c0a6e1c3b9a2
+updated: 2017-05-10T10:36:40.145873342+02:00
+imports:
+- name: github.com/pkg/errors
+  version: 645ef00459ed84a119197bfb8d8205042c6df63d
+- name: golang.org/x/net
+  version: f2499483f923065a842d38eb4c7f1927e6fc6e6d
+  repo: git@github.com:golang/net
+  subpackages:
+  - context
+testImports:
+- name: github.com/stretchr/testify
+  version: "v1.1.4" # comment
+`,
+			expected: []depEntry{
+				{importPath: "github.com/pkg/errors", rev: "645ef00459ed84a119197bfb8d8205042c6df63d"},
+				{importPath: "golang.org/x/net", rev: "f2499483f923065a842d38eb4c7f1927e6fc6e6d", repoPath: "https://github.com/golang/net"},
+				{importPath: "github.com/stretchr/testify", rev: "v1.1.4"},
+			},
+		},
+		{
+			name: "Gopkg.lock",
+			data: `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+[[projects]]
+  name = "github.com/pkg/errors"
+  packages = ["."]
+  revision = "645ef00459ed84a119197bfb8d8205042c6df63d"
+  version = "v0.8.0"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/net"
+  packages = ["context"]
+  revision = "f2499483f923065a842d38eb4c7f1927e6fc6e6d"
+  source = "github.com/golang/net"
+
+[solve-meta]
+  analyzer-name = "dep"
+  inputs-digest = "d7c1c5a4a2a5b4f1c5b1f0c2c3a0d3b7e0a8f8c0c4d2b2a3c8b7f6e1d0c9b8a7"
+`,
+			expected: []depEntry{
+				{importPath: "github.com/pkg/errors", rev: "645ef00459ed84a119197bfb8d8205042c6df63d"},
+				{importPath: "golang.org/x/net", rev: "f2499483f923065a842d38eb4c7f1927e6fc6e6d", repoPath: "https://github.com/golang/net"},
+			},
+		},
+		{
+			name: "vendor.json",
+			data: `{
+	"comment": "",
+	"ignore": "test",
+	"package": [
+		{
+			"checksumSHA1": "ynJSWoF6v+3zMnh9R0QmmG6iGV8=",
+			"path": "github.com/pkg/errors",
+			"revision": "645ef00459ed84a119197bfb8d8205042c6df63d",
+			"revisionTime": "2016-09-29T01:48:01Z"
+		}
+	],
+	"rootPath": "github.com/example/project"
+}`,
+			expected: []depEntry{
+				{importPath: "github.com/pkg/errors", rev: "645ef00459ed84a119197bfb8d8205042c6df63d"},
+			},
+		},
+		{
+			name: "trash.yml",
+			data: `package: github.com/example/project
+
+import:
+  - package: github.com/pkg/errors
+    version: v0.8.0
+  - package: golang.org/x/net
+    version: f2499483f923065a842d38eb4c7f1927e6fc6e6d
+    repo: https://github.com/golang/net.git
+`,
+			expected: []depEntry{
+				{importPath: "github.com/pkg/errors", rev: "v0.8.0"},
+				{importPath: "golang.org/x/net", rev: "f2499483f923065a842d38eb4c7f1927e6fc6e6d", repoPath: "https://github.com/golang/net.git"},
+			},
+		},
+	}
+	parsers := map[string]func(string) ([]depEntry, error){}
+	for _, m := range manifests {
+		parse := m.parse
+		parsers[filepath.Base(m.file)] = func(s string) ([]depEntry, error) {
+			return parse(strings.NewReader(s))
+		}
+	}
+	for _, c := range cases {
+		deps, err := parsers[c.name](c.data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(deps, c.expected) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.expected, deps)
+		}
+	}
+}
+
+func TestPinsLookup(t *testing.T) {
+	p := pins{}
+	p.add([]depEntry{
+		{importPath: "github.com/x/y", rev: "v1"},
+		{importPath: "github.com/x/z/b", rev: "v2"},
+		{importPath: "github.com/x/z/a", rev: "v3"},
+		{importPath: "github.com/x/norev"},
+	})
+	p.add([]depEntry{{importPath: "github.com/x/y", rev: "v4"}})
+	cases := []struct {
+		imp, root, rev string
+	}{
+		{"github.com/x/y", "github.com/x/y", "v1"},
+		{"github.com/x/y/sub", "github.com/x/y", "v1"},
+		{"github.com/x/z/b/c", "github.com/x/z", "v2"},
+		{"github.com/x/z/c", "github.com/x/z", "v3"},
+		{"github.com/x/yy", "github.com/x/yy", ""},
+		{"github.com/x/norev", "github.com/x/norev", ""},
+	}
+	for _, c := range cases {
+		d, ok := p.lookup(c.imp, c.root)
+		if ok != (c.rev != "") || d.rev != c.rev {
+			t.Fatalf("%s: expected revision %q, got %q", c.imp, c.rev, d.rev)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/LK4D4/vndr/godl"
+)
+
+// freshness describes how far behind its repository a dependency is.
+type freshness struct {
+	ImportPath string    `json:"importPath"`
+	Revision   string    `json:"revision"`
+	Commit     string    `json:"commit,omitempty"`
+	LatestTag  string    `json:"latestTag,omitempty"`
+	Behind     int       `json:"behind"`
+	CommitTime time.Time `json:"commitTime"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// checkFreshness finds the newest tag of repository of d, the number of
+// commits of default branch after pinned commit and the time of this commit.
+func checkFreshness(d depEntry) (freshness, error) {
+	f := freshness{ImportPath: d.importPath, Revision: d.rev}
+	v, err := resolve(d)
+	if err != nil {
+		return f, err
+	}
+	refs, err := godl.RemoteRefs(v.Type, v.Repo)
+	if err != nil {
+		return f, err
+	}
+	f.LatestTag, _ = latestTag(refs, -1)
+	if f.Commit, err = resolveRev(d); err != nil {
+		return f, err
+	}
+	if head, ok := refs["HEAD"]; ok {
+		if f.Behind, err = godl.CommitsBehind(v.Type, v.Repo, f.Commit, head); err != nil {
+			return f, err
+		}
+	}
+	if f.CommitTime, err = godl.CommitTime(v.Type, v.Repo, f.Commit); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// outdated writes freshness of deps to w as table or JSON.
+func outdated(w io.Writer, deps []depEntry, asJSON bool) error {
+	res := make([]freshness, len(deps))
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, 16)
+	for i, d := range deps {
+		wg.Add(1)
+		go func(i int, d depEntry) {
+			limit <- struct{}{}
+			f, err := checkFreshness(d)
+			if err != nil {
+				f.Error = err.Error()
+			}
+			res[i] = f
+			<-limit
+			wg.Done()
+		}(i, d)
+	}
+	wg.Wait()
+	failed := 0
+	for _, f := range res {
+		if f.Error != "" {
+			failed++
+		}
+	}
+	if err := printFreshness(w, res, asJSON); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("Failed to check %d dependencies", failed)
+	}
+	return nil
+}
+
+func printFreshness(w io.Writer, res []freshness, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(res)
+	}
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMPORT PATH\tREVISION\tLATEST TAG\tBEHIND\tAGE")
+	for _, f := range res {
+		if f.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\terror: %s\t\t\n", f.ImportPath, f.Revision, f.Error)
+			continue
+		}
+		tag := f.LatestTag
+		if tag == "" {
+			tag = "-"
+		}
+		age := strconv.Itoa(int(now.Sub(f.CommitTime).Hours()/24)) + "d"
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", f.ImportPath, f.Revision, tag, f.Behind, age)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LK4D4/vndr/godl"
+)
+
+func TestPrintFreshness(t *testing.T) {
+	commitTime := time.Now().Add(-49 * time.Hour).UTC().Truncate(time.Second)
+	res := []freshness{
+		{ImportPath: "github.com/pkg/errors", Revision: "v0.8.0", Commit: "645ef00459ed84a119197bfb8d8205042c6df63d", LatestTag: "v0.9.1", Behind: 47, CommitTime: commitTime},
+		{ImportPath: "github.com/foo/notags", Revision: "c5613b87bafaaf105fd3857dcae7ef23c931feec", Commit: "c5613b87bafaaf105fd3857dcae7ef23c931feec", CommitTime: commitTime},
+		{ImportPath: "github.com/foo/gone", Revision: "v1.0.0", Error: "repository not found"},
+	}
+	var buf bytes.Buffer
+	if err := printFreshness(&buf, res, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"IMPORT PATH            REVISION                                  LATEST TAG                   BEHIND  AGE",
+		"github.com/pkg/errors  v0.8.0                                    v0.9.1                       47      2d",
+		"github.com/foo/notags  c5613b87bafaaf105fd3857dcae7ef23c931feec  -                            0       2d",
+		"github.com/foo/gone    v1.0.0                                    error: repository not found",
+	}
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+
+	buf.Reset()
+	if err := printFreshness(&buf, res, true); err != nil {
+		t.Fatal(err)
+	}
+	var parsed []freshness
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, res) {
+		t.Fatalf("expected %+v, got %+v", res, parsed)
+	}
+	if n := strings.Count(buf.String(), `"latestTag"`); n != 1 {
+		t.Fatalf("empty latest tag must be omitted:\n%s", buf.String())
+	}
+}
+
+func TestCheckFreshnessError(t *testing.T) {
+	d := depEntry{importPath: "example.com/svn", rev: "1234", repoPath: "https://example.com/svn", vcs: "svn"}
+	f, err := checkFreshness(d)
+	if err == nil {
+		t.Fatal("expected error for repository without references")
+	}
+	if f.ImportPath != d.importPath || f.Revision != d.rev {
+		t.Fatalf("unexpected freshness %+v", f)
+	}
+}
+
+func TestCheckFreshnessBehind(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "vndr-outdated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	repo := filepath.Join(tmp, "repo")
+	git := gitRepo(t, repo)
+	for i, name := range []string{"a", "b", "c"} {
+		writeFiles(t, repo, map[string]string{name: name})
+		git("add", name)
+		git("commit", "-q", "-m", name)
+		if i == 0 {
+			git("tag", "v1.0.0")
+		}
+	}
+
+	defer func(dir string) { godl.CacheDir = dir }(godl.CacheDir)
+	godl.CacheDir = filepath.Join(tmp, "cache")
+	d := depEntry{importPath: "example.com/repo", rev: "v1.0.0", repoPath: repo, vcs: "git"}
+	f, err := checkFreshness(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Commit != git("rev-parse", "v1.0.0") || f.LatestTag != "v1.0.0" || f.Behind != 2 || f.CommitTime.IsZero() {
+		t.Fatalf("unexpected freshness %+v", f)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	gobuild "go/build"
 	"log"
 	"os"
 	"path/filepath"
@@ -18,6 +19,8 @@ var (
 		CgoEnabled:  true,
 		GOROOT:      runtime.GOROOT(),
 		GOPATH:      os.Getenv("GOPATH"),
+		// release tags matter only for files of -platform targets
+		ReleaseTags: gobuild.Default.ReleaseTags,
 	}
 )
 
@@ -29,7 +32,10 @@ func init() {
 	ctx.GOPATH = gp
 }
 
-func collectAllDeps(wd string, dlFunc func(imp string) (*build.Package, error), initPkgs ...*build.Package) ([]*build.Package, error) {
+// collectAllDeps collects packages imported by initPkgs and their
+// dependencies. Packages extraImports are collected even if nothing imports
+// them.
+func collectAllDeps(wd string, dlFunc func(imp string) (*build.Package, error), extraImports []string, initPkgs ...*build.Package) ([]*build.Package, error) {
 	pkgCache := make(map[string]*build.Package)
 	var deps []*build.Package
 	initPkgsMap := make(map[*build.Package]bool)
@@ -39,6 +45,42 @@ func collectAllDeps(wd string, dlFunc func(imp string) (*build.Package, error),
 		pkgCache[imp] = pkg
 		deps = append(deps, pkg)
 	}
+	visit := func(imp string) {
+		if imp == "C" {
+			return
+		}
+		if _, ok := pkgCache[imp]; ok {
+			return
+		}
+		ipkg, err := ctx.Import(imp, wd, 0)
+		if ipkg.Goroot {
+			return
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "cannot find package ") && dlFunc != nil {
+				ipkg, err = dlFunc(imp)
+			}
+		} else if !strings.HasPrefix(ipkg.Dir, wd) {
+			// dependency not in vendor
+			if dlFunc != nil {
+				ipkg, err = dlFunc(imp)
+			} else {
+				Warnf("dependency is not vendored: %s", imp)
+			}
+
+		}
+		if _, ok := err.(*build.MultiplePackageError); !ok && err != nil {
+			if verbose {
+				log.Printf("\tWARNING(verbose) %s: %v", imp, err)
+			}
+			return
+		}
+		pkgCache[imp] = ipkg
+		deps = append(deps, ipkg)
+	}
+	for _, imp := range extraImports {
+		visit(imp)
+	}
 	for len(deps) != 0 {
 		pkg := deps[len(deps)-1]
 		deps = deps[:len(deps)-1]
@@ -48,37 +90,7 @@ func collectAllDeps(wd string, dlFunc func(imp string) (*build.Package, error),
 			imports = append(imports, pkg.XTestImports...)
 		}
 		for _, imp := range imports {
-			if imp == "C" {
-				continue
-			}
-			if _, ok := pkgCache[imp]; ok {
-				continue
-			}
-			ipkg, err := ctx.Import(imp, wd, 0)
-			if ipkg.Goroot {
-				continue
-			}
-			if err != nil {
-				if strings.Contains(err.Error(), "cannot find package ") && dlFunc != nil {
-					ipkg, err = dlFunc(imp)
-				}
-			} else if !strings.HasPrefix(ipkg.Dir, wd) {
-				// dependency not in vendor
-				if dlFunc != nil {
-					ipkg, err = dlFunc(imp)
-				} else {
-					Warnf("dependency is not vendored: %s", imp)
-				}
-
-			}
-			if _, ok := err.(*build.MultiplePackageError); !ok && err != nil {
-				if verbose {
-					log.Printf("\tWARNING(verbose) %s: %v", imp, err)
-				}
-				continue
-			}
-			pkgCache[imp] = ipkg
-			deps = append(deps, ipkg)
+			visit(imp)
 		}
 	}
 	var pkgs []*build.Package
@@ -88,6 +100,22 @@ func collectAllDeps(wd string, dlFunc func(imp string) (*build.Package, error),
 	return pkgs, nil
 }
 
+// subpackageImports returns import paths of subpackages listed by deps.
+func subpackageImports(deps []depEntry) []string {
+	var imps []string
+	for _, d := range deps {
+		for _, sub := range d.subpackages {
+			sub = strings.Trim(sub, "/")
+			if sub == "." || sub == "" {
+				imps = append(imps, d.importPath)
+				continue
+			}
+			imps = append(imps, d.importPath+"/"+sub)
+		}
+	}
+	return imps
+}
+
 func collectPkgs(dir string) ([]*build.Package, error) {
 	var pkgs []*build.Package
 	err := filepath.Walk(dir, func(path string, i os.FileInfo, err error) error {
@@ -98,7 +126,7 @@ func collectPkgs(dir string) ([]*build.Package, error) {
 			return nil
 		}
 		// skip vendoring directory itself
-		if path == filepath.Join(dir, vendorDir) {
+		if path == filepath.Join(dir, vendorDir) || path == filepath.Join(dir, stagingDir) || path == filepath.Join(dir, oldDir) {
 			return filepath.SkipDir
 		}
 		pkg, err := ctx.ImportDir(path, build.ImportMode(0))
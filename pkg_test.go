@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/LK4D4/vndr/build"
+)
+
+func TestImportTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vndr-targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.go":              "package a\n",
+		"a_linux.go":        "package a\n",
+		"a_windows.go":      "package a\n",
+		"a_darwin_arm64.go": "package a\n",
+		"a_arm64.s":         "",
+		"a_386.s":           "",
+		"netgo.go":          "// +build netgo\n\npackage a\n",
+		"nocgo.go":          "// +build !cgo\n\npackage a\n",
+		"plan9.go":          "// +build plan9\n\npackage a\n",
+		"unix.go":           "// +build unix\n\npackage a\n",
+		"new.go":            "// +build go1.9\n\npackage a\n",
+		"gen.go":            "// +build ignore\n\npackage main\n",
+	}
+	writeFiles(t, dir, files)
+	var platforms platformSlice
+	for _, p := range []string{"linux/amd64", "linux/arm64", "windows/amd64,netgo"} {
+		if err := platforms.Set(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := *ctx
+	c.Targets = platforms
+	pkg, err := c.ImportDir(dir, build.ImportMode(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pkg.GoFiles)
+	expected := []string{"a.go", "a_linux.go", "a_windows.go", "netgo.go", "new.go", "nocgo.go", "unix.go"}
+	if !reflect.DeepEqual(pkg.GoFiles, expected) {
+		t.Fatalf("expected files %v, got %v", expected, pkg.GoFiles)
+	}
+	if !reflect.DeepEqual(pkg.SFiles, []string{"a_arm64.s"}) {
+		t.Fatalf("expected assembly files [a_arm64.s], got %v", pkg.SFiles)
+	}
+	if !reflect.DeepEqual(pkg.IgnoredOtherFiles, []string{"a_386.s"}) {
+		t.Fatalf("expected ignored files [a_386.s], got %v", pkg.IgnoredOtherFiles)
+	}
+	for _, p := range []string{"linux", "linux/amd64/extra", "foo/amd64"} {
+		if err := platforms.Set(p); err == nil {
+			t.Fatalf("%q: expected error", p)
+		}
+	}
+}
+
+func TestImportGoBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vndr-gobuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.go":        "package a\n",
+		"expr.go":     "//go:build (linux || darwin) && amd64\n\npackage a\n",
+		"arm.go":      "//go:build (linux || darwin) && arm64\n\npackage a\n",
+		"override.go": "//go:build windows\n// +build linux\n\npackage a\n",
+		"legacy.go":   "// +build linux\n\npackage a\n",
+		"tools.go":    "//go:build tools\n\npackage a\n",
+		"notools.go":  "//go:build windows && !tools\n\npackage a\n",
+		"nolegacy.go": "// +build windows,!tools\n\npackage a\n",
+		"either.go":   "//go:build windows && (tools || ignore)\n\npackage a\n",
+		"gen.go":      "//go:build ignore\n\npackage main\n",
+		"doc.go":      "// Package a.\n//go:build windows\npackage a\n",
+		"bad.go":      "//go:build linux &&\n\npackage a\n",
+	}
+	writeFiles(t, dir, files)
+	cases := []struct {
+		targets []build.Target
+		files   []string
+	}{
+		{nil, []string{"a.go", "arm.go", "doc.go", "expr.go", "legacy.go", "nolegacy.go", "notools.go", "override.go"}},
+		{[]build.Target{{GOOS: "linux", GOARCH: "amd64"}}, []string{"a.go", "expr.go", "legacy.go"}},
+		{[]build.Target{{GOOS: "windows", GOARCH: "arm64", BuildTags: []string{"tools"}}}, []string{"a.go", "doc.go", "nolegacy.go", "notools.go", "override.go"}},
+	}
+	for _, tc := range cases {
+		c := *ctx
+		c.IgnoreTags = []string{"ignore", "tools"}
+		c.Targets = tc.targets
+		pkg, err := c.ImportDir(dir, build.ImportMode(0))
+		if err != nil {
+			t.Fatalf("%v: invalid //go:build line must not fail the package: %v", tc.targets, err)
+		}
+		if !reflect.DeepEqual(pkg.InvalidGoFiles, []string{"bad.go"}) {
+			t.Fatalf("%v: expected invalid files [bad.go], got %v", tc.targets, pkg.InvalidGoFiles)
+		}
+		sort.Strings(pkg.GoFiles)
+		if !reflect.DeepEqual(pkg.GoFiles, tc.files) {
+			t.Fatalf("%v: expected files %v, got %v", tc.targets, tc.files, pkg.GoFiles)
+		}
+	}
+}
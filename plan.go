@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// printPlan prints what vendoring of deps would do with vendor directory
+// without changing anything. If whole is true, deps are all dependencies
+// from config and vendor directory is going to be recreated. Unchanged deps
+// are kept as is.
+func printPlan(vd string, deps []depEntry, unchanged map[string]lockEntry, whole bool) error {
+	fmt.Println("Repositories to clone:")
+	var roots []string
+	for _, d := range deps {
+		root, err := rootImport(d)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, root)
+		if _, ok := unchanged[d.importPath]; ok {
+			continue
+		}
+		v, err := resolve(d)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.importPath, err)
+		}
+		fmt.Printf("\t%s %s %s, revision %s\n", root, v.Type, v.Repo, d.rev)
+	}
+	fmt.Println("Vendor directories:")
+	for _, r := range roots {
+		action := "add"
+		if _, ok := unchanged[r]; ok {
+			action = "keep"
+		} else if _, err := os.Stat(filepath.Join(vd, r)); err == nil {
+			action = "replace"
+		}
+		fmt.Printf("\t%s %s\n", action, filepath.Join(vendorDir, r))
+	}
+	if !whole {
+		return nil
+	}
+	extra, err := extraPaths(vd, roots)
+	if err != nil {
+		return err
+	}
+	for _, p := range extra {
+		fmt.Printf("\tdelete %s\n", filepath.Join(vendorDir, p))
+	}
+	if len(cleanWhitelist) > 0 {
+		log.Println("Paths matching whitelist are not deleted")
+	}
+	log.Println("Unused packages inside of cloned repositories are deleted after download")
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// rules by which cleaning removes paths from vendor dir
+const (
+	ruleUnused       = "unused package"
+	ruleTestFile     = "test file"
+	ruleIgnoredGo    = "ignored Go file"
+	ruleIgnored      = "ignored file"
+	ruleDotfile      = "dotfile"
+	ruleUnderscore   = "underscore file"
+	ruleTestdata     = "testdata"
+	ruleNestedVendor = "nested vendor dir"
+	ruleNonGo        = "non-Go file"
+	ruleVCS          = "vcs metadata"
+	ruleDrop         = "drop rule"
+)
+
+// removal is a path removed from vendor dir by cleaning.
+type removal struct {
+	Path string `json:"path"`
+	Rule string `json:"rule"`
+}
+
+// removalLog collects paths removed by cleanVendor and cleanVCS, it's safe for
+// concurrent use.
+type removalLog struct {
+	mu      sync.Mutex
+	removed []removal
+}
+
+// cleanReport is not nil if removed paths are reported.
+var cleanReport *removalLog
+
+// add records that path relative to vendor dir was removed by rule. It does
+// nothing on nil log.
+func (l *removalLog) add(path, rule string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.removed = append(l.removed, removal{Path: filepath.ToSlash(path), Rule: rule})
+	l.mu.Unlock()
+}
+
+// print writes removed paths sorted by path to w as table or JSON.
+func (l *removalLog) print(w io.Writer, asJSON bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sort.Slice(l.removed, func(i, j int) bool { return l.removed[i].Path < l.removed[j].Path })
+	if asJSON {
+		removed := l.removed
+		if removed == nil {
+			removed = []removal{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(removed)
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tRULE")
+	for _, r := range l.removed {
+		fmt.Fprintf(tw, "%s\t%s\n", r.Path, r.Rule)
+	}
+	return tw.Flush()
+}
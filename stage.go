@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// New vendor tree is built in stagingDir and replaces vendor directory only
+// if vendoring succeeded.
+const (
+	stagingDir = "vendor.tmp"
+	oldDir     = "vendor.old"
+)
+
+// copyTree copies src directory to dst, directories from exclude are skipped.
+// Files are hardlinked where possible, vendoring never modifies files in
+// place, it only removes them. Existing files in dst are replaced.
+func copyTree(src, dst string, exclude map[string]bool) error {
+	return filepath.Walk(src, func(path string, i os.FileInfo, err error) error {
+		if err != nil {
+			if path == src && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if i.IsDir() {
+			if exclude[path] {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, i.Mode().Perm()|0700)
+		}
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if i.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		if err := os.Link(path, target); err == nil {
+			return nil
+		}
+		return copyFile(path, target, i.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// swapVendor replaces vd with staging directory. If staging directory doesn't
+// exist, vd is removed.
+func swapVendor(staging, vd string) error {
+	old := filepath.Join(filepath.Dir(vd), oldDir)
+	if err := os.RemoveAll(old); err != nil {
+		return err
+	}
+	if err := os.Rename(vd, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(staging, vd); err != nil && !os.IsNotExist(err) {
+		// put the old tree back
+		os.Rename(old, vd)
+		return err
+	}
+	return os.RemoveAll(old)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/LK4D4/vndr/build"
+	"github.com/LK4D4/vndr/godl"
+)
+
+// Modes of resolving dependencies missing in vendor.conf.
+const (
+	transitiveSuggest = "suggest"
+	transitiveAdd     = "add"
+)
+
+// nestedManifests are configs of vendored dependencies, which are kept by
+// cleaning and pin dependencies of these dependencies.
+var nestedManifests = []manifest{
+	{configFile, parseDeps},
+	{goModFile, parseGoModDeps},
+}
+
+func parseGoModDeps(r io.Reader) ([]depEntry, error) {
+	return readGoModDeps(r, resolveModule)
+}
+
+// parseRawGoModDeps is like parseGoModDeps, but module paths are taken as
+// import paths without looking up their repositories by network.
+func parseRawGoModDeps(r io.Reader) ([]depEntry, error) {
+	return readGoModDeps(r, func(path string) (*godl.VCS, error) {
+		return &godl.VCS{ImportPath: path}, nil
+	})
+}
+
+func readGoModDeps(r io.Reader, resolve func(path string) (*godl.VCS, error)) ([]depEntry, error) {
+	m, err := parseGoMod(r)
+	if err != nil {
+		return nil, err
+	}
+	return goModDeps(m, resolve, func(format string, a ...interface{}) {
+		if verbose {
+			log.Printf("WARNING(verbose): "+format, a...)
+		}
+	}), nil
+}
+
+// nestedPin is a dependency pinned by config of vendored dependency by.
+type nestedPin struct {
+	by  string
+	dep depEntry
+}
+
+// nestedPins are dependencies pinned by configs of vendored dependencies,
+// keyed by import path.
+type nestedPins map[string][]nestedPin
+
+// read reads configs ms of dependency imp vendored in vd and returns
+// dependencies pinned by them.
+func (n nestedPins) read(vd, imp string, ms []manifest) ([]depEntry, error) {
+	deps, files, err := readManifests(filepath.Join(vd, imp), ms)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", imp, err)
+	}
+	for _, f := range files {
+		if verbose {
+			log.Printf("\tUsing %s of %s", f, imp)
+		}
+	}
+	var pinned []depEntry
+	for _, d := range deps {
+		if d.importPath == imp || d.rev == "" {
+			continue
+		}
+		n[d.importPath] = append(n[d.importPath], nestedPin{by: imp, dep: d})
+		pinned = append(pinned, d)
+	}
+	return pinned, nil
+}
+
+// transitive resolves dependencies missing in vendor.conf by revisions pinned
+// in configs of vendored dependencies.
+type transitive struct {
+	add      bool
+	pinned   pins
+	pinnedBy nestedPins
+	// resolved are dependencies added or suggested to vendor.conf
+	resolved []depEntry
+}
+
+func newTransitive(mode string) *transitive {
+	return &transitive{
+		add:      mode == transitiveAdd,
+		pinned:   pins{},
+		pinnedBy: nestedPins{},
+	}
+}
+
+// read reads configs of dependency imp vendored in vd. Dependencies read
+// earlier have precedence.
+func (t *transitive) read(vd, imp string) error {
+	deps, err := t.pinnedBy.read(vd, imp, nestedManifests)
+	if err != nil {
+		return err
+	}
+	t.pinned.add(deps)
+	return nil
+}
+
+// checkConflicts warns if dependencies pin importPath at different revisions.
+func (t *transitive) checkConflicts(importPath string) {
+	var wants []string
+	revs := make(map[string]bool)
+	for _, p := range t.pinnedBy[importPath] {
+		revs[p.dep.rev] = true
+		wants = append(wants, fmt.Sprintf("%s by %s", p.dep.rev, p.by))
+	}
+	if len(revs) > 1 {
+		Warnf("%s is pinned at different revisions: %s", importPath, strings.Join(wants, ", "))
+	}
+}
+
+// dlFunc returns function for collectAllDeps, which resolves imports missing
+// in vendor dir staging. Imports of cfgDeps and imports without pinned
+// revisions are passed to next, which can be nil. Vendored dependencies are
+// recorded to lock.
+func (t *transitive) dlFunc(wd, staging string, cfgDeps []depEntry, lock *[]lockEntry, next func(string) (*build.Package, error)) func(string) (*build.Package, error) {
+	if next == nil {
+		next = func(imp string) (*build.Package, error) {
+			return importVendored(wd, imp)
+		}
+	}
+	return func(imp string) (*build.Package, error) {
+		if depOf(imp, cfgDeps) || depOf(imp, t.resolved) {
+			return next(imp)
+		}
+		root, err := rootImport(depEntry{importPath: imp})
+		if err != nil {
+			// pins of imp and its parents still can be found
+			root = imp
+		}
+		d, ok := t.pinned.lookup(imp, root)
+		if !ok {
+			return next(imp)
+		}
+		t.checkConflicts(d.importPath)
+		by := t.pinnedBy[d.importPath][0].by
+		t.resolved = append(t.resolved, d)
+		if !t.add {
+			Warnf("dependency %s is not in %s, %s pins %s", imp, configFile, by, strings.TrimSpace(d.String()))
+			return next(imp)
+		}
+		log.Printf("\tAdd %s, revision %s, pinned by %s", d.importPath, d.rev, by)
+		l, err := cloneDep(staging, d)
+		if err != nil {
+			return nil, err
+		}
+		*lock = mergeLock(*lock, []lockEntry{l})
+		if err := t.read(staging, d.importPath); err != nil {
+			return nil, err
+		}
+		return importVendored(wd, imp)
+	}
+}
+
+// depOf reports whether package imp belongs to one of deps.
+func depOf(imp string, deps []depEntry) bool {
+	for _, d := range deps {
+		if imp == d.importPath || strings.HasPrefix(imp, d.importPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// appendConfig appends deps to the end of config file.
+func appendConfig(deps []depEntry, file string) error {
+	c, err := readConfig(file)
+	if err != nil {
+		return err
+	}
+	for _, d := range deps {
+		c.add(d)
+	}
+	return c.write(file)
+}
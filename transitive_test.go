@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestTransitiveRead(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-transitive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	writeFiles(t, vd, map[string]string{
+		"github.com/a/a/vendor.conf": "github.com/c/c v1.0.0\ngithub.com/a/a v0.1.0\n",
+		"github.com/b/b/go.mod":      "module github.com/b/b\n\nrequire (\n\tgithub.com/c/c v2.0.0+incompatible\n\tgithub.com/d/d v0.0.0-20200323222414-85ca7c5b95cd\n)\n",
+	})
+	tr := newTransitive(transitiveSuggest)
+	for _, imp := range []string{"github.com/a/a", "github.com/b/b", "github.com/e/e"} {
+		if err := tr.read(vd, imp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := tr.pinned.lookup("github.com/a/a", "github.com/a/a"); ok {
+		t.Fatal("dependency must not pin itself")
+	}
+	d, ok := tr.pinned.lookup("github.com/c/c/sub", "github.com/c/c")
+	if !ok || d.rev != "v1.0.0" {
+		t.Fatalf("expected github.com/c/c pinned at v1.0.0 by first dependency, got %v", d)
+	}
+	if len(tr.pinnedBy["github.com/c/c"]) != 2 {
+		t.Fatalf("expected github.com/c/c pinned by two dependencies, got %v", tr.pinnedBy["github.com/c/c"])
+	}
+	d, ok = tr.pinned.lookup("github.com/d/d", "github.com/d/d")
+	if !ok || d.rev != "85ca7c5b95cd" {
+		t.Fatalf("expected github.com/d/d pinned at 85ca7c5b95cd, got %v", d)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/LK4D4/vndr/godl"
+	"github.com/LK4D4/vndr/versioned"
+)
+
+// latestTag returns the newest semantic version tag from refs, prereleases
+// are skipped. If major is not negative, only tags of this major version are
+// considered.
+func latestTag(refs map[string]string, major int) (string, bool) {
+	var latest string
+	var latestV versioned.Semver
+	for ref := range refs {
+		if !strings.HasPrefix(ref, "refs/tags/") {
+			continue
+		}
+		tag := strings.TrimPrefix(ref, "refs/tags/")
+		v, ok := versioned.ParseSemver(tag)
+		if !ok || v.Prerelease != "" || (major >= 0 && v.Major != major) {
+			continue
+		}
+		if latest == "" || v.Compare(latestV) > 0 || (v.Compare(latestV) == 0 && tag < latest) {
+			latest, latestV = tag, v
+		}
+	}
+	return latest, latest != ""
+}
+
+// updatedRev returns the newest revision of d: the head of branch if it is
+// not empty, otherwise the newest tag of the same major version or the head
+// of default branch if there are no tags. Commits of import paths without
+// major version suffix are moved only to v0 or v1 tags. Revision of d is
+// returned if it is a newer tag or a commit or branch which already contains
+// the newest tag.
+func updatedRev(d depEntry, branch string) (string, error) {
+	v, err := resolve(d)
+	if err != nil {
+		return "", err
+	}
+	refs, err := godl.RemoteRefs(v.Type, v.Repo)
+	if err != nil {
+		return "", err
+	}
+	if branch != "" {
+		commit, ok := refs["refs/heads/"+branch]
+		if !ok {
+			return "", fmt.Errorf("branch %s is not found in %s", branch, v.Repo)
+		}
+		return commit, nil
+	}
+	var tag string
+	var ok bool
+	current, isTag := versioned.ParseSemver(d.rev)
+	if isTag {
+		tag, ok = latestTag(refs, current.Major)
+	} else if m := pathMajor(d.importPath); m >= 2 {
+		tag, ok = latestTag(refs, m)
+	} else if tag, ok = latestTag(refs, 1); !ok {
+		// import path without major version suffix can't import v2+ tags
+		tag, ok = latestTag(refs, 0)
+	}
+	if ok {
+		if v, _ := versioned.ParseSemver(tag); isTag && v.Compare(current) <= 0 {
+			return d.rev, nil
+		}
+		if !isTag {
+			ahead, err := containsTag(d, v, refs["refs/tags/"+tag])
+			if err != nil {
+				return "", err
+			}
+			if ahead {
+				return d.rev, nil
+			}
+		}
+		return tag, nil
+	}
+	if isTag {
+		return d.rev, nil
+	}
+	head, ok := refs["HEAD"]
+	if !ok {
+		return "", fmt.Errorf("default branch is not found in %s", v.Repo)
+	}
+	if strings.HasPrefix(head, d.rev) {
+		return d.rev, nil
+	}
+	return head, nil
+}
+
+// containsTag reports whether commit or branch of d is ahead of tagCommit of
+// its repository v, so switching to the tag would be a downgrade.
+func containsTag(d depEntry, v *godl.VCS, tagCommit string) (bool, error) {
+	commit, err := resolveRev(d)
+	if err != nil {
+		return false, err
+	}
+	if strings.HasPrefix(tagCommit, commit) {
+		return false, nil
+	}
+	n, err := godl.CommitsBehind(v.Type, v.Repo, commit, tagCommit)
+	if err != nil {
+		return false, fmt.Errorf("unable to compare %s with tag: %v", d.rev, err)
+	}
+	return n == 0, nil
+}
+
+// updateDeps finds the newest revisions of dependencies with import paths
+// imps, all deps are updated if imps is empty. It returns all deps with new
+// revisions and updated deps only.
+func updateDeps(deps []depEntry, imps []string, branch string) ([]depEntry, []depEntry, error) {
+	selected := make(map[string]bool)
+	for _, imp := range imps {
+		selected[imp] = false
+	}
+	var all, updated []depEntry
+	for _, d := range deps {
+		if _, ok := selected[d.importPath]; !ok && len(imps) > 0 {
+			all = append(all, d)
+			continue
+		}
+		selected[d.importPath] = true
+		rev, err := updatedRev(d, branch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", d.importPath, err)
+		}
+		if rev == d.rev {
+			log.Printf("\t%s is up to date, revision %s", d.importPath, d.rev)
+			all = append(all, d)
+			continue
+		}
+		log.Printf("\tUpdate %s from %s to %s", d.importPath, d.rev, rev)
+		d.rev = rev
+		all = append(all, d)
+		updated = append(updated, d)
+	}
+	for imp, found := range selected {
+		if !found {
+			return nil, nil, fmt.Errorf("Failed to find %s in config file", imp)
+		}
+	}
+	return all, updated, nil
+}
+
+// setConfigRevs rewrites revisions of updated deps in config file in place.
+// Comments and alignment of columns are kept.
+func setConfigRevs(file string, updated []depEntry) error {
+	c, err := readConfig(file)
+	if err != nil {
+		return err
+	}
+	revs := make(map[string]string)
+	for _, d := range updated {
+		revs[d.importPath] = d.rev
+	}
+	for _, d := range c.deps() {
+		if rev, ok := revs[d.importPath]; ok {
+			d.rev = rev
+			c.set(d.importPath, d)
+		}
+	}
+	return c.write(file)
+}
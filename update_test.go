@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LK4D4/vndr/godl"
+)
+
+func TestLatestTag(t *testing.T) {
+	refs := map[string]string{
+		"HEAD":                  "a",
+		"refs/heads/master":     "a",
+		"refs/heads/v9.0.0":     "b",
+		"refs/tags/v1.2.0":      "c",
+		"refs/tags/v1.10.0":     "d",
+		"refs/tags/v1.11.0-rc1": "e",
+		"refs/tags/v2.0.0":      "f",
+		"refs/tags/release":     "g",
+	}
+	cases := []struct {
+		major int
+		tag   string
+	}{
+		{-1, "v2.0.0"},
+		{1, "v1.10.0"},
+		{3, ""},
+	}
+	for _, c := range cases {
+		if tag, _ := latestTag(refs, c.major); tag != c.tag {
+			t.Fatalf("major %d: expected %q, got %q", c.major, c.tag, tag)
+		}
+	}
+}
+
+func TestUpdatedRevMajor(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "vndr-update")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	repo := filepath.Join(tmp, "repo")
+	git := gitRepo(t, repo)
+	var commits []string
+	for i, name := range []string{"a", "b", "c"} {
+		writeFiles(t, repo, map[string]string{name: name})
+		git("add", name)
+		git("commit", "-q", "-m", name)
+		commits = append(commits, git("rev-parse", "HEAD"))
+		switch i {
+		case 0:
+			git("tag", "v1.0.0")
+		case 2:
+			git("tag", "v2.0.0")
+		}
+	}
+
+	defer func(dir string) { godl.CacheDir = dir }(godl.CacheDir)
+	godl.CacheDir = filepath.Join(tmp, "cache")
+	cases := []struct {
+		importPath string
+		rev        string
+		expected   string
+	}{
+		{"example.com/repo", commits[0], "v1.0.0"},
+		{"example.com/repo", commits[1], commits[1]},
+		{"example.com/repo", "v1.0.0", "v1.0.0"},
+		{"example.com/repo/v2", commits[0], "v2.0.0"},
+	}
+	for _, c := range cases {
+		d := depEntry{importPath: c.importPath, rev: c.rev, repoPath: repo, vcs: "git"}
+		rev, err := updatedRev(d, "")
+		if err != nil {
+			t.Fatalf("%s %s: %v", c.importPath, c.rev, err)
+		}
+		if rev != c.expected {
+			t.Fatalf("%s %s: expected %s, got %s", c.importPath, c.rev, c.expected, rev)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// verifyVendor compares vendor directory with config entries and hashes
+// recorded in the lock file. It returns the list of found problems.
+func verifyVendor(vd string, deps []depEntry, lock []lockEntry) ([]string, error) {
+	var problems []string
+	locked := make(map[string]lockEntry)
+	var imps []string
+	for _, l := range lock {
+		locked[l.importPath] = l
+		imps = append(imps, l.importPath)
+	}
+	configured := make(map[string]bool)
+	for _, d := range deps {
+		configured[d.importPath] = true
+		l, ok := locked[d.importPath]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing %s: not found in %s", d.importPath, lockFile))
+			continue
+		}
+		if l.rev != d.rev || (d.repoPath != "" && l.repo != d.repoPath) {
+			problems = append(problems, fmt.Sprintf("modified %s: %s has %s %s, %s has %s %s", d.importPath, configFile, d.rev, d.repoPath, lockFile, l.rev, l.repo))
+			continue
+		}
+		dir := filepath.Join(vd, d.importPath)
+		if _, err := os.Stat(dir); err != nil && os.IsNotExist(err) && l.hash != emptyHash {
+			problems = append(problems, fmt.Sprintf("missing %s: directory %s does not exist", d.importPath, dir))
+			continue
+		}
+		h, err := hashDir(dir, nestedRoots(vd, d.importPath, imps))
+		if err != nil {
+			return nil, err
+		}
+		if h != l.hash {
+			problems = append(problems, fmt.Sprintf("modified %s: hash %s, expected %s", d.importPath, h, l.hash))
+		}
+	}
+	for _, l := range lock {
+		if !configured[l.importPath] {
+			problems = append(problems, fmt.Sprintf("extra %s: not found in %s", l.importPath, configFile))
+		}
+	}
+	var roots []string
+	for imp := range configured {
+		roots = append(roots, imp)
+	}
+	extra, err := extraPaths(vd, roots)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range extra {
+		problems = append(problems, fmt.Sprintf("extra %s: does not belong to any package from %s", p, configFile))
+	}
+	return problems, nil
+}
+
+// extraPaths returns paths inside vendor directory which are not under any of
+// roots. Paths are relative to vendor directory.
+func extraPaths(vd string, roots []string) ([]string, error) {
+	var extra []string
+	err := filepath.Walk(vd, func(path string, i os.FileInfo, err error) error {
+		if err != nil {
+			if path == vd && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if path == vd || path == filepath.Join(vd, modulesTxtFile) {
+			return nil
+		}
+		rel, err := filepath.Rel(vd, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		for _, r := range roots {
+			if rel == r {
+				return filepath.SkipDir
+			}
+			if i.IsDir() && strings.HasPrefix(r, rel+"/") {
+				return nil
+			}
+		}
+		extra = append(extra, rel)
+		if i.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return extra, err
+}
+
+// verify checks that vendor directory matches vendor.conf and vendor.sum
+// without touching network.
+func verify(vd string) error {
+	deps, err := readDeps()
+	if err != nil {
+		return err
+	}
+	lock, err := readLock(lockFile)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return fmt.Errorf("%s is not found, run vndr to create it", lockFile)
+	}
+	problems, err := verifyVendor(vd, deps, lock)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("vendor directory does not match %s, found %d problems", configFile, len(problems))
+	}
+	log.Println("vendor directory matches", configFile)
+	return nil
+}
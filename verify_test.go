@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVerifyVendor(t *testing.T) {
+	vd, err := ioutil.TempDir("", "vndr-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(vd)
+	writeFiles(t, vd, map[string]string{
+		"github.com/foo/bar/pkg.go":   "package pkg\n",
+		"github.com/foo/baz/pkg.go":   "package pkg\n",
+		"github.com/extra/pkg/pkg.go": "package pkg\n",
+	})
+	deps := []depEntry{
+		{importPath: "github.com/foo/bar", rev: "v1.0.0"},
+		{importPath: "github.com/foo/baz", rev: "master"},
+		{importPath: "github.com/foo/missing", rev: "master"},
+	}
+	lock := []lockEntry{
+		{importPath: "github.com/foo/bar", rev: "v1.0.0"},
+		{importPath: "github.com/foo/baz", rev: "master"},
+	}
+	if err := hashLock(vd, lock); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := verifyVendor(vd, deps[:2], lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(problems, []string{"extra github.com/extra: does not belong to any package from vendor.conf"}) {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+
+	writeFiles(t, vd, map[string]string{"github.com/foo/baz/pkg.go": "package changed\n"})
+	problems, err = verifyVendor(vd, deps, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, p := range problems {
+		kinds = append(kinds, strings.SplitN(p, ":", 2)[0])
+	}
+	expected := []string{
+		"modified github.com/foo/baz",
+		"missing github.com/foo/missing",
+		"extra github.com/extra",
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Fatalf("expected %v, got %v", expected, problems)
+	}
+}
@@ -2,6 +2,8 @@ package versioned
 
 import (
 	"regexp"
+	"strconv"
+	"strings"
 )
 
 var re = regexp.MustCompile(`/v[0-9]+$`)
@@ -10,3 +12,77 @@ var re = regexp.MustCompile(`/v[0-9]+$`)
 func IsVersioned(s string) bool {
 	return re.MatchString(s)
 }
+
+var (
+	semverRe = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+	pseudoRe = regexp.MustCompile(`-(?:[0-9A-Za-z.-]*\.)?[0-9]{14}-([0-9a-f]{12})(?:\+incompatible)?$`)
+)
+
+// Semver is a parsed semantic version like v1.2.3-rc.1+build.
+type Semver struct {
+	Major, Minor, Patch int
+	Prerelease          string
+	Build               string
+}
+
+// ParseSemver parses semantic version with "v" prefix. ok is false if s is
+// not a semantic version.
+func ParseSemver(s string) (v Semver, ok bool) {
+	m := semverRe.FindStringSubmatch(s)
+	if m == nil {
+		return Semver{}, false
+	}
+	var err error
+	if v.Major, err = strconv.Atoi(m[1]); err != nil {
+		return Semver{}, false
+	}
+	if v.Minor, err = strconv.Atoi(m[2]); err != nil {
+		return Semver{}, false
+	}
+	if v.Patch, err = strconv.Atoi(m[3]); err != nil {
+		return Semver{}, false
+	}
+	v.Prerelease = strings.TrimPrefix(m[4], "-")
+	v.Build = strings.TrimPrefix(m[5], "+")
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than w.
+// Build metadata is not taken into account, prereleases are compared as
+// strings.
+func (v Semver) Compare(w Semver) int {
+	for _, d := range []int{v.Major - w.Major, v.Minor - w.Minor, v.Patch - w.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == w.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case w.Prerelease == "":
+		return -1
+	case v.Prerelease < w.Prerelease:
+		return -1
+	}
+	return 1
+}
+
+// IsPseudo is true for Go modules pseudo-versions like
+// "v0.0.0-20191109021931-daa7c04131f5".
+func IsPseudo(version string) bool {
+	return pseudoRe.MatchString(version)
+}
+
+// Revision returns revision of Go module version for version control system:
+// abbreviated commit hash for pseudo-versions and tag for other versions.
+func Revision(version string) string {
+	if m := pseudoRe.FindStringSubmatch(version); m != nil {
+		return m[1]
+	}
+	return strings.TrimSuffix(version, "+incompatible")
+}
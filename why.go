@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// edgeSources are fields of build.Package which edges of graph come from.
+var edgeSources = map[string]string{
+	edgeImport: "Imports",
+	edgeTest:   "TestImports",
+	edgeXTest:  "XTestImports",
+}
+
+// chainLink is a package of import chain and the kind of edge by which the
+// previous package imports it.
+type chainLink struct {
+	ImportPath string `json:"importPath"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+// importChain returns the shortest chain of imports from roots of g to package
+// imp or to any package under imp. Imports of a package are visited before
+// its test imports.
+func importChain(g *depGraph, imp string) ([]chainLink, bool) {
+	var queue []string
+	for n := range g.roots {
+		queue = append(queue, n)
+	}
+	sort.Strings(queue)
+	prev := make(map[string]string)
+	visited := make(map[string]bool)
+	for _, n := range queue {
+		visited[n] = true
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if n == imp || strings.HasPrefix(n, imp+"/") {
+			chain := []chainLink{{ImportPath: n}}
+			for p, ok := prev[n]; ok; p, ok = prev[p] {
+				chain[0].Kind = g.edges[p][chain[0].ImportPath]
+				chain = append([]chainLink{{ImportPath: p}}, chain...)
+			}
+			return chain, true
+		}
+		var next []string
+		for to := range g.edges[n] {
+			if !visited[to] {
+				next = append(next, to)
+			}
+		}
+		sort.Slice(next, func(i, j int) bool {
+			ki, kj := edgeRank[g.edges[n][next[i]]], edgeRank[g.edges[n][next[j]]]
+			if ki != kj {
+				return ki < kj
+			}
+			return next[i] < next[j]
+		})
+		for _, to := range next {
+			visited[to] = true
+			prev[to] = n
+			queue = append(queue, to)
+		}
+	}
+	return nil, false
+}
+
+// why writes the shortest import chain from packages of the project to package
+// imp to w as text or JSON.
+func why(w io.Writer, g *depGraph, imp string, asJSON bool) error {
+	chain, ok := importChain(g, imp)
+	if !ok {
+		return fmt.Errorf("%s is not imported by packages of the project", imp)
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(chain)
+	}
+	fmt.Fprintf(w, "# %s\n%s\n", imp, chain[0].ImportPath)
+	for _, l := range chain[1:] {
+		fmt.Fprintf(w, "\t%s (%s)\n", l.ImportPath, edgeSources[l.Kind])
+	}
+	return nil
+}
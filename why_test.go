@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWhy(t *testing.T) {
+	g := newDepGraph()
+	g.roots["example.com/p"] = true
+	g.roots["example.com/p/cmd"] = true
+	g.addEdge("example.com/p", "github.com/a/a", edgeImport)
+	g.addEdge("example.com/p", "github.com/c/c", edgeXTest)
+	g.addEdge("example.com/p/cmd", "github.com/c/c", edgeTest)
+	g.addEdge("github.com/a/a", "github.com/b/b/sub", edgeImport)
+	g.addEdge("github.com/c/c", "github.com/b/b/sub", edgeImport)
+
+	cases := []struct {
+		imp      string
+		expected string
+	}{
+		{"github.com/b/b", "# github.com/b/b\nexample.com/p\n\tgithub.com/a/a (Imports)\n\tgithub.com/b/b/sub (Imports)\n"},
+		{"github.com/c/c", "# github.com/c/c\nexample.com/p\n\tgithub.com/c/c (XTestImports)\n"},
+		{"example.com/p/cmd", "# example.com/p/cmd\nexample.com/p/cmd\n"},
+		{"github.com/b/b/sub", "# github.com/b/b/sub\nexample.com/p\n\tgithub.com/a/a (Imports)\n\tgithub.com/b/b/sub (Imports)\n"},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		if err := why(&out, g, c.imp, false); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.expected {
+			t.Fatalf("%s: expected %q, got %q", c.imp, c.expected, out.String())
+		}
+	}
+	if err := why(&bytes.Buffer{}, g, "github.com/d/d", false); err == nil {
+		t.Fatal("expected error for package which is not imported")
+	}
+}